@@ -3,10 +3,11 @@ package kokoro
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"mime/multipart"
 	"net"
 	"net/http"
-	"sort"
+	"net/netip"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,6 +23,11 @@ type Context struct {
 	noCopy nocopy.NoCopy        // nolint:structcheck,unused
 	ctx    *fasthttp.RequestCtx // The underlying fasthttp request context.
 	server *Server              // A reference to the Kokoro server instance.
+	group  *Router              // The Router group the matched route was registered on.
+	route  string               // The pattern of the matched route (e.g., "/users/{id}").
+
+	logger     *slog.Logger       // The request-scoped logger, see Logger.
+	propagated []propagatedHeader // Headers registered with PropagateHeader and KeepHeader.
 
 	cache struct { // Cache for frequently accessed request properties to optimize performance.
 		method      string
@@ -30,6 +36,8 @@ type Context struct {
 		baseURL     string
 		hostname    string
 		protocol    string
+		peer        peer
+		resolved    bool
 	}
 }
 
@@ -53,6 +61,10 @@ func acquireContext(fctx *fasthttp.RequestCtx, server *Server) *Context {
 // This should be called once a request has been fully processed.
 func releaseContext(c *Context) {
 	c.ctx = nil
+	c.group = nil
+	c.route = ""
+	c.logger = nil
+	c.propagated = c.propagated[:0]
 	// Reset the cache to clear any previous request's data.
 	c.cache = struct {
 		method      string
@@ -61,10 +73,32 @@ func releaseContext(c *Context) {
 		baseURL     string
 		hostname    string
 		protocol    string
+		peer        peer
+		resolved    bool
 	}{}
 	contextPool.Put(c)
 }
 
+// RequestCtx returns the underlying fasthttp.RequestCtx, giving middlewares
+// direct access to the raw request and response.
+func (c *Context) RequestCtx() *fasthttp.RequestCtx {
+	return c.ctx
+}
+
+// Route returns the pattern of the matched route, including its group prefix
+// (e.g., "/users/{id}"). It is empty when no route matched the request.
+func (c *Context) Route() string {
+	return c.route
+}
+
+// HandleError passes err to the error handlers of the matched route's group, its
+// parent groups and the Server, writing the error response immediately.
+// Handlers and middlewares returning an error get this for free; middlewares can call it
+// to observe the final response, e.g. to log its status, and then return nil.
+func (c *Context) HandleError(err error) {
+	c.server.handleError(c, c.group, err)
+}
+
 // Method returns the HTTP method of the request (e.g., "GET", "POST").
 // The result is cached for subsequent calls within the same request.
 func (c *Context) Method() string {
@@ -93,28 +127,25 @@ func (c *Context) URL() string {
 }
 
 // BaseURL returns the base URL of the request, including the scheme and host (e.g., "http://example.com" or "https://api.domain.com").
+// Behind a trusted proxy, the scheme and host the client requested are used; see Scheme and Host.
 // The result is cached for subsequent calls within the same request.
 func (c *Context) BaseURL() string {
 	if c.cache.baseURL == "" {
-		scheme := "http"
-		if c.ctx.IsTLS() {
-			scheme = "https"
-		}
-		c.cache.baseURL = scheme + "://" + string(c.ctx.Host())
+		c.cache.baseURL = c.Scheme() + "://" + c.peer().host
 	}
 	return c.cache.baseURL
 }
 
 // Host returns the hostname of the request, potentially without the port if present (e.g., "example.com" from "example.com:8080").
+// Behind a trusted proxy, the host comes from the Forwarded or X-Forwarded-Host header.
 // The result is cached for subsequent calls within the same request.
 func (c *Context) Host() string {
 	if c.cache.hostname == "" {
-		host, _, err := net.SplitHostPort(string(c.ctx.Host()))
-		if err != nil {
-			c.cache.hostname = string(c.ctx.Host())
-		} else {
-			c.cache.hostname = host
+		host := c.peer().host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
 		}
+		c.cache.hostname = host
 	}
 	return c.cache.hostname
 }
@@ -176,21 +207,18 @@ func (c *Context) GetForwardedIPs() []string {
 	return parts
 }
 
-// RealIP attempts to determine the client's real IP address.
-// It first checks the X-Forwarded-For header (taking the first IP)
-// and falls back to the direct remote IP if the header is not present.
+// RealIP returns the client's IP address.
+//
+// When the request comes from one of the Server's TrustedProxies, the headers
+// listed in Server.ProxyHeaders are consulted in order. Forwarding chains are
+// walked right to left, skipping trusted proxies, so addresses forged by the
+// client are ignored. Otherwise the remote IP of the connection is returned.
 func (c *Context) RealIP() string {
-	xForwardedFor := c.Header(HeaderForwardedFor)
-	if xForwardedFor != "" {
-		parts := strings.Split(string(xForwardedFor), ",")
-		if len(parts) > 0 {
-			return strings.TrimSpace(parts[0])
-		}
-	}
-	return c.ctx.RemoteIP().String()
+	return c.peer().ip
 }
 
 // QueryParams parses and returns all query parameters as a map[string]string.
+// Repeated keys keep only their last value; use QueryAll to read all of them.
 func (c *Context) QueryParams() map[string]string {
 	queryArgs := c.ctx.QueryArgs()
 	params := make(map[string]string, queryArgs.Len())
@@ -213,6 +241,21 @@ func (c *Context) Query(key string, defaultValue ...string) string {
 	return ""
 }
 
+// QueryAll retrieves every value of a repeated query parameter by its key,
+// in the order they appear in the query string (e.g., ["a", "b"] for "?tag=a&tag=b").
+// Returns nil if the key is not present.
+func (c *Context) QueryAll(key string) []string {
+	values := c.ctx.QueryArgs().PeekMulti(key)
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
 // Header retrieves the value of a specific request header by its key.
 func (c *Context) Header(key string) string {
 	return string(c.ctx.Request.Header.Peek(key))
@@ -220,10 +263,51 @@ func (c *Context) Header(key string) string {
 
 // SetHeader sets a specific response header with the given key and value.
 func (c *Context) SetHeader(key, value string) {
-	c.ctx.Request.Header.Set(key, value)
+	c.ctx.Response.Header.Set(key, value)
+}
+
+// HeaderValues retrieves every value of a request header by its key,
+// including repeated header lines. Returns nil if the header is not present.
+func (c *Context) HeaderValues(key string) []string {
+	values := c.ctx.Request.Header.PeekAll(key)
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// Vary adds the given request header names to the Vary response header,
+// skipping names already listed.
+func (c *Context) Vary(fields ...string) {
+	existing := string(c.ctx.Response.Header.Peek(HeaderVary))
+	for _, field := range fields {
+		found := false
+		for _, v := range strings.Split(existing, ",") {
+			if v = strings.TrimSpace(v); v == "*" || strings.EqualFold(v, field) {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if existing == "" {
+			existing = field
+		} else {
+			existing += ", " + field
+		}
+	}
+	if existing != "" {
+		c.ctx.Response.Header.Set(HeaderVary, existing)
+	}
 }
 
 // Headers returns all request headers as a map[string]string.
+// Repeated headers keep only their last value; use HeaderValues to read all of them.
 func (c *Context) Headers() map[string]string {
 	headers := make(map[string]string)
 	c.ctx.Request.Header.VisitAll(func(key, value []byte) {
@@ -239,67 +323,77 @@ type acceptItem struct {
 }
 
 // parseAccept parses a given Accept-like header string (e.g., Accept, Accept-Charset)
-// into a sorted slice of acceptItem structs, ordered by quality factor (q) in descending order.
+// into a slice of acceptItem structs, in the order of the header.
 func parseAccept(header string) []acceptItem {
 	parts := strings.Split(header, ",")
 	items := make([]acceptItem, 0, len(parts))
 
 	for _, part := range parts {
-		part = strings.TrimSpace(part)
+		value, params, _ := strings.Cut(part, ";")
 		q := 1.0 // Default quality factor
-		if idx := strings.Index(part, ";q="); idx != -1 {
-			qValStr := part[idx+3:]
-			part = part[:idx]
-			if qVal, err := strconv.ParseFloat(qValStr, 64); err == nil {
-				q = qVal
+		for _, param := range strings.Split(params, ";") {
+			param = strings.TrimSpace(param)
+			if len(param) > 2 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+				if qVal, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = qVal
+				}
 			}
 		}
-		items = append(items, acceptItem{value: strings.ToLower(part), q: q})
+		items = append(items, acceptItem{value: strings.ToLower(strings.TrimSpace(value)), q: q})
 	}
-
-	// Sort by quality factor in descending order.
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].q > items[j].q
-	})
-
 	return items
 }
 
 // matchAccept is a utility function that attempts to match the given header (e.g., Accept header value)
-// against a list of offers (e.g., supported content types). It returns the best match according to
-// the Accept header's quality factors, or an empty string if no match is found.
-// It supports wildcards (* and type/*).
+// against a list of offers (e.g., supported content types). It returns the offer with the highest
+// quality factor, or an empty string if no offer is acceptable or the header is empty.
+//
+// Each offer takes the quality factor of the most specific value matching it, as defined by
+// RFC 9110, Section 12.5.1: an exact match, then a type wildcard (type/*), then * or */*.
+// Offers whose factor is 0 are never returned, even when a wildcard accepts them, and
+// ties go to the earliest offer, so offers should be listed in order of preference.
 func matchAccept(header string, offers []string) string {
-	if header == "" || len(offers) == 0 {
+	if header == "" {
 		return ""
 	}
-
 	accepted := parseAccept(header)
-	offersLower := make([]string, len(offers))
-	for i, o := range offers {
-		offersLower[i] = strings.ToLower(o)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q, _ := acceptQuality(accepted, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
 	}
+	return best
+}
 
+// acceptQuality returns the quality factor the most specific value of accepted
+// matching offer gives it, and whether any value matched.
+func acceptQuality(accepted []acceptItem, offer string) (float64, bool) {
+	offer = strings.ToLower(offer)
+	q, specificity := 0.0, -1
 	for _, acc := range accepted {
-		for i, offer := range offersLower {
-			// Exact match or wildcard *
-			if acc.value == offer || acc.value == "*" {
-				return offers[i]
-			}
-			// Type wildcard match (e.g., "text/*" matching "text/html")
-			if strings.HasSuffix(acc.value, "/*") {
-				prefix := strings.TrimSuffix(acc.value, "*")
-				if strings.HasPrefix(offer, prefix) {
-					return offers[i]
-				}
-			}
+		s := -1
+		switch {
+		case acc.value == offer:
+			s = 2
+		case acc.value == "*" || acc.value == "*/*":
+			s = 0
+		case strings.HasSuffix(acc.value, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(acc.value, "*")):
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = acc.q, s
 		}
 	}
-	return ""
+	return q, specificity >= 0
 }
 
 // Accepts determines the best content type that the client accepts based on the
 // Accept request header and the provided offers.
+// Each offer takes the quality factor of the most specific media range matching it,
+// offers given q=0 are never returned, and ties go to the earliest offer, so offers
+// should be listed in order of preference. The same rules apply to the other
+// Accepts methods.
 func (c *Context) Accepts(offers ...string) string {
 	return matchAccept(c.Header(HeaderAccept), offers)
 }
@@ -311,9 +405,22 @@ func (c *Context) AcceptsCharset(offers ...string) string {
 }
 
 // AcceptsEncoding determines the best encoding that the client accepts based on the
-// Accept-Encoding request header and the provided offers.
+// Accept-Encoding request header and the provided offers. "identity", when offered,
+// is acceptable unless refused with "identity;q=0" or "*;q=0", as defined by
+// RFC 9110, Section 12.5.3, and chosen when no other offer is.
 func (c *Context) AcceptsEncoding(offers ...string) string {
-	return matchAccept(c.Header(HeaderAcceptEncoding), offers)
+	header := c.Header(HeaderAcceptEncoding)
+	if best := matchAccept(header, offers); best != "" {
+		return best
+	}
+	for _, offer := range offers {
+		if strings.EqualFold(offer, "identity") {
+			if q, matched := acceptQuality(parseAccept(header), offer); !matched || q > 0 {
+				return offer
+			}
+		}
+	}
+	return ""
 }
 
 // AcceptsLanguage determines the best language that the client accepts based on the
@@ -336,11 +443,13 @@ type Range struct {
 // Ranges parses the Range request header. It validates the header format and the unit
 // (must be "bytes"). It converts the range specifications into HTTPRange structs,
 // adjusting them to fit within maxSize.
-// Returns a *Range struct or an error if the header is invalid or unsupported.
+// Returns a *Range struct or an error if the header is invalid or unsupported:
+// ErrNoRange without a Range header, and ErrRangeNotSatisfiable when no range
+// overlaps the content. SendRange writes the corresponding partial response.
 func (c *Context) Ranges(maxSize int64) (*Range, error) {
-	header := c.Header("Range")
+	header := c.Header(HeaderRange)
 	if header == "" {
-		return nil, errors.New("no Range header")
+		return nil, ErrNoRange
 	}
 
 	parts := strings.SplitN(header, "=", 2)
@@ -407,7 +516,7 @@ func (c *Context) Ranges(maxSize int64) (*Range, error) {
 	}
 
 	if len(ranges) == 0 {
-		return nil, errors.New("no valid byte ranges found in header")
+		return nil, ErrRangeNotSatisfiable
 	}
 
 	return &Range{
@@ -425,16 +534,15 @@ func max(a, b int64) int64 {
 }
 
 // Scheme returns the scheme of the request ("http" or "https").
+// Behind a trusted proxy, the scheme comes from the Forwarded or X-Forwarded-Proto header.
 func (c *Context) Scheme() string {
-	if c.ctx.IsTLS() {
-		return "https"
-	}
-	return "http"
+	return c.peer().scheme
 }
 
-// IsSecure returns true if the request was made over a TLS (HTTPS) connection.
+// IsSecure returns true if the request was made over HTTPS, either directly or
+// to a trusted proxy.
 func (c *Context) IsSecure() bool {
-	return c.ctx.IsTLS()
+	return c.Scheme() == "https"
 }
 
 // Subdomains extracts and returns the subdomains from the request host.
@@ -455,38 +563,34 @@ func (c *Context) Subdomains(offset ...int) []string {
 	return parts[:len(parts)-n] // Return parts before the offset
 }
 
-// Fresh checks if the request is "fresh" based on If-None-Match (ETag) and If-Modified-Since (Last-Modified) headers.
-// Returns true if the client's cached version is still valid, indicating that a 304 Not Modified response can be sent.
+// Fresh reports whether the client's cached representation is still valid, so a
+// 304 Not Modified response can be sent instead of the full one.
+// It evaluates the request's If-None-Match and If-Modified-Since headers against the
+// ETag and Last-Modified headers of the response, which must therefore be set before
+// calling it. Following RFC 9110, Section 13.2.2, If-Modified-Since is ignored when
+// If-None-Match is present, and only applies to GET and HEAD requests.
+// Requests with "Cache-Control: no-cache" are never fresh.
 func (c *Context) Fresh() bool {
-	ifNoneMatch := c.Header(HeaderIfNoneMatch)
-	etag := c.Header(HeaderETag)
-	if ifNoneMatch != "" && etag != "" {
-		// Strong ETag comparison
-		if ifNoneMatch == etag {
-			return true
-		}
-		// Weak ETag comparison
-		if strings.HasPrefix(ifNoneMatch, "W/") && strings.HasSuffix(etag, ifNoneMatch[2:]) {
-			return true
-		}
+	if strings.Contains(strings.ToLower(c.Header(HeaderCacheControl)), "no-cache") {
+		return false
 	}
 
-	ifModifiedSince := c.Header(HeaderIfModifiedSince)
-	lastModified := c.Header(HeaderLastModified)
-
-	if ifModifiedSince != "" && lastModified != "" {
-		modTime, err1 := http.ParseTime(ifModifiedSince)
-		lastTime, err2 := http.ParseTime(lastModified)
+	if ifNoneMatch := c.Header(HeaderIfNoneMatch); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, string(c.ctx.Response.Header.Peek(HeaderETag)), false)
+	}
 
-		if err1 == nil && err2 == nil {
-			// Check if the content has not been modified since the client's cached version.
-			// The !lastTime.After(modTime) means lastTime is equal to or before modTime.
-			if !lastTime.After(modTime) {
-				return true
-			}
-		}
+	if method := c.Method(); method != MethodGet && method != MethodHead {
+		return false
+	}
+	ifModifiedSince := c.Header(HeaderIfModifiedSince)
+	lastModified := string(c.ctx.Response.Header.Peek(HeaderLastModified))
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
 	}
-	return false
+	modTime, err1 := http.ParseTime(ifModifiedSince)
+	lastTime, err2 := http.ParseTime(lastModified)
+	// The representation is unchanged if it was last modified at or before the cached copy.
+	return err1 == nil && err2 == nil && !lastTime.After(modTime)
 }
 
 // Stale returns true if the request is "stale", meaning the client's cached version is no longer valid
@@ -495,6 +599,17 @@ func (c *Context) Stale() bool {
 	return !c.Fresh()
 }
 
+// NotModified turns the response into a bodiless 304 Not Modified. Unlike
+// fasthttp's RequestCtx.NotModified, it keeps the response headers, such as ETag,
+// Cache-Control and Vary, that RFC 9110, Section 15.4.5 requires on a 304.
+func (c *Context) NotModified() error {
+	c.ctx.Response.ResetBody()
+	c.ctx.Response.Header.Del(HeaderContentType)
+	c.ctx.Response.Header.SetNoDefaultContentType(true)
+	c.ctx.SetStatusCode(StatusNotModified)
+	return nil
+}
+
 // IsXHR returns true if the X-Requested-With header is "XMLHttpRequest", indicating an AJAX request.
 func (c *Context) IsXHR() bool {
 	return c.Header(HeaderXRequestedWith) == "XMLHttpRequest"
@@ -610,11 +725,11 @@ func (c *Context) StatusCode() int {
 // by the kokoro server configuration. This is important for correctly determining the client's
 // real IP when behind load balancers or CDNs.
 func (c *Context) IsProxyTrusted() bool {
-	ip := net.ParseIP(c.ctx.RemoteIP().String())
-	if ip == nil || c.server == nil {
+	if c.server == nil {
 		return false
 	}
-	return c.server.isTrustedProxy(ip)
+	ip, ok := netip.AddrFromSlice(c.ctx.RemoteIP())
+	return ok && c.server.proxies().trusts(ip)
 }
 
 // SendFile writes the file at the given path to the response body.
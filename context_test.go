@@ -0,0 +1,92 @@
+package kokoro
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestAccepts(t *testing.T) {
+	html, json, text := "text/html", "application/json", "text/plain"
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		offers []string
+		want   string
+	}{
+		{"exact", HeaderAccept, "application/json", []string{html, json}, json},
+		{"case-insensitive", HeaderAccept, "Application/JSON", []string{html, json}, json},
+		{"highest q", HeaderAccept, "text/html;q=0.5, application/json;q=0.8", []string{html, json}, json},
+		{"q parameter after others", HeaderAccept, "text/html;level=1;q=0.2, application/json;q=0.3", []string{html, json}, json},
+		{"tie goes to offer order", HeaderAccept, "application/json, text/html", []string{html, json}, html},
+		{"tie with wildcard", HeaderAccept, "*/*", []string{json, html}, json},
+		{"type wildcard", HeaderAccept, "text/*", []string{json, text}, text},
+		{"exact beats type wildcard", HeaderAccept, "text/*;q=0.5, text/html", []string{text, html}, html},
+		{"exact q applies over wildcard", HeaderAccept, "text/*, text/html;q=0.1", []string{html, text}, text},
+		{"type wildcard beats any", HeaderAccept, "*/*;q=0.1, text/*;q=0.9", []string{json, text}, text},
+		{"q=0 refuses", HeaderAccept, "application/json;q=0", []string{json}, ""},
+		{"q=0 refuses despite wildcard", HeaderAccept, "application/json;q=0, */*", []string{json, html}, html},
+		{"q=0 type wildcard", HeaderAccept, "text/*;q=0, */*;q=0.5", []string{html, json}, json},
+		{"q=0 everything", HeaderAccept, "*/*;q=0", []string{html, json}, ""},
+		{"invalid q ignored", HeaderAccept, "text/html;q=abc", []string{html}, html},
+		{"no match", HeaderAccept, "image/png", []string{html, json}, ""},
+		{"empty header", HeaderAccept, "", []string{html, json}, ""},
+		{"no offers", HeaderAccept, "*/*", nil, ""},
+		{"charset", HeaderAcceptCharset, "iso-8859-1;q=0.5, utf-8", []string{"iso-8859-1", "utf-8"}, "utf-8"},
+		{"language", HeaderAcceptLanguage, "fr;q=0.9, en", []string{"fr", "en"}, "en"},
+		{"encoding", HeaderAcceptEncoding, "gzip, br", []string{"br", "gzip"}, "br"},
+		{"encoding any", HeaderAcceptEncoding, "*", []string{"zstd", "gzip"}, "zstd"},
+		{"encoding any but one", HeaderAcceptEncoding, "*, zstd;q=0", []string{"zstd", "gzip"}, "gzip"},
+		{"encoding q", HeaderAcceptEncoding, "gzip;q=1.0, br;q=0.5", []string{"br", "gzip"}, "gzip"},
+		{"identity implicit", HeaderAcceptEncoding, "gzip", []string{"br", "identity"}, "identity"},
+		{"identity without header", HeaderAcceptEncoding, "", []string{"br", "identity"}, "identity"},
+		{"identity after others", HeaderAcceptEncoding, "gzip", []string{"identity", "gzip"}, "gzip"},
+		{"identity refused", HeaderAcceptEncoding, "identity;q=0", []string{"br", "identity"}, ""},
+		{"identity refused by any", HeaderAcceptEncoding, "*;q=0", []string{"identity"}, ""},
+		{"identity allowed over any", HeaderAcceptEncoding, "*;q=0, identity", []string{"gzip", "identity"}, "identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			if tt.value != "" {
+				ctx.Request.Header.Set(tt.header, tt.value)
+			}
+			c := &Context{ctx: &ctx}
+
+			var got string
+			switch tt.header {
+			case HeaderAccept:
+				got = c.Accepts(tt.offers...)
+			case HeaderAcceptCharset:
+				got = c.AcceptsCharset(tt.offers...)
+			case HeaderAcceptLanguage:
+				got = c.AcceptsLanguage(tt.offers...)
+			case HeaderAcceptEncoding:
+				got = c.AcceptsEncoding(tt.offers...)
+			}
+			if got != tt.want {
+				t.Errorf("%s: %q offered %q = %q, want %q", tt.header, tt.value, tt.offers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetHeader(t *testing.T) {
+	s := New()
+	s.GET("/", func(c *Context) error {
+		c.SetHeader("X-Version", "1")
+		c.SetHeader("X-Version", "2")
+		return c.SendText("ok")
+	})
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/")
+	s.Handler(&ctx)
+
+	if got := string(ctx.Response.Header.Peek("X-Version")); got != "2" {
+		t.Errorf("response X-Version = %q, want %q", got, "2")
+	}
+	if got := ctx.Request.Header.Peek("X-Version"); got != nil {
+		t.Errorf("request X-Version = %q, want it unset", got)
+	}
+}
@@ -0,0 +1,192 @@
+package kokoro
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnsafePath is wrapped by the 400 *HTTPError returned by Download for names
+// that are not local to their root directory.
+var ErrUnsafePath = errors.New("unsafe file path")
+
+// Attachment sets the Content-Disposition header so the response is downloaded
+// rather than displayed. When filename is not empty, it is suggested to the client
+// as defined by RFC 6266, with a UTF-8 variant for non-ASCII names, and the
+// Content-Type is guessed from its extension. Directory components are stripped
+// from filename, so it can safely come from user input.
+func (c *Context) Attachment(filename string) {
+	c.setDisposition("attachment", filename)
+}
+
+// Inline is like Attachment, but asks the client to display the response, while
+// still suggesting filename for when it is saved.
+func (c *Context) Inline(filename string) {
+	c.setDisposition("inline", filename)
+}
+
+// Download sends the file called name within the directory root as an attachment,
+// named after the file itself or filename when given. Range requests are supported
+// through SendRange, so interrupted downloads can be resumed.
+//
+// name may come from user input: it is opened with os.OpenInRoot, so neither ".."
+// elements nor symbolic links can reach outside root. Names that are absolute or
+// lexically escape root, such as "../secret", and names leading outside root
+// through a symbolic link are rejected with 400; missing files and directories
+// result in 404.
+//
+// Example:
+//
+//	s.GET("/files/{name:*}", func(c *kokoro.Context) error {
+//	    return c.Download("uploads", c.Param("name"))
+//	})
+func (c *Context) Download(root, name string, filename ...string) error {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		return NewHTTPError(StatusBadRequest, "Invalid file path").WithCause(ErrUnsafePath)
+	}
+
+	f, err := os.OpenInRoot(root, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return NewHTTPError(StatusNotFound).WithCause(err)
+		}
+		if pathErr := (*fs.PathError)(nil); errors.As(err, &pathErr) {
+			// Such as a symbolic link leading outside root.
+			return NewHTTPError(StatusBadRequest, "Invalid file path").WithCause(errors.Join(ErrUnsafePath, err))
+		}
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if info.IsDir() {
+		_ = f.Close()
+		return NewHTTPError(StatusNotFound)
+	}
+
+	attachment := filepath.Base(name)
+	if len(filename) > 0 && filename[0] != "" {
+		attachment = filename[0]
+	}
+	c.Attachment(attachment)
+	return c.SendRange(f, info.Size(), info.ModTime())
+}
+
+// SendStream streams r as the response body. size is the length of the body, or -1
+// when unknown, in which case chunked transfer encoding is used. When no Content-Type
+// has been set, it is sniffed from the first bytes of r. If r implements io.Closer,
+// it is closed once the body has been written.
+func (c *Context) SendStream(r io.Reader, size int) error {
+	if explicitContentType(&c.ctx.Response.Header) == "" {
+		br := bufio.NewReaderSize(r, 512)
+		head, err := br.Peek(512)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			closeContent(r)
+			return err
+		}
+		c.ContentType(http.DetectContentType(head))
+		r = &bufferedReadCloser{Reader: br, src: r}
+	}
+	c.ctx.SetBodyStream(r, size)
+	return nil
+}
+
+// bufferedReadCloser reads through a bufio.Reader while keeping the source closable.
+type bufferedReadCloser struct {
+	*bufio.Reader
+	src io.Reader
+}
+
+func (b *bufferedReadCloser) Close() error {
+	closeContent(b.src)
+	return nil
+}
+
+// setDisposition sets the Content-Disposition header of the given type for filename.
+func (c *Context) setDisposition(kind, filename string) {
+	filename = sanitizeFilename(filename)
+	if filename == "" {
+		c.ctx.Response.Header.Set(HeaderContentDisposition, kind)
+		return
+	}
+	if contentType := mime.TypeByExtension(path.Ext(filename)); contentType != "" {
+		c.ContentType(contentType)
+	}
+	c.ctx.Response.Header.Set(HeaderContentDisposition, contentDisposition(kind, filename))
+}
+
+// contentDisposition formats a Content-Disposition value. Names that are not plain
+// ASCII get an ASCII fallback in filename and the exact name in filename*, encoded
+// as defined by RFC 8187.
+func contentDisposition(kind, filename string) string {
+	var fallback strings.Builder
+	plain := true
+	for _, r := range filename {
+		switch {
+		case r == '"' || r == '\\':
+			fallback.WriteByte('_')
+		case r >= 0x20 && r < 0x7f:
+			fallback.WriteRune(r)
+		default:
+			fallback.WriteByte('_')
+			plain = false
+		}
+	}
+
+	value := kind + `; filename="` + fallback.String() + `"`
+	if !plain {
+		value += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return value
+}
+
+// encodeExtValue percent-encodes s, keeping the attr-char set of RFC 8187, Section 3.2.1.
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch < utf8.RuneSelf && (isAlnum(ch) || strings.IndexByte("!#$&+-.^_`|~", ch) >= 0) {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0x0f])
+	}
+	return b.String()
+}
+
+func isAlnum(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+// sanitizeFilename reduces a possibly user-supplied name to a bare file name,
+// without directory components, control characters or invalid UTF-8.
+func sanitizeFilename(name string) string {
+	name = strings.ToValidUTF8(name, "")
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = strings.TrimSpace(path.Base(name))
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,161 @@
+package kokoro
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestDownload(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "files")
+	for name, data := range map[string]string{
+		"files/report.pdf": "%PDF-1.4 report",
+		"files/docs/a.txt": "a",
+		"files/docs/b.txt": "b",
+		"secret.txt":       "secret",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "escape")); err != nil {
+		t.Skip("symbolic links unsupported:", err)
+	}
+	if err := os.Symlink("docs/a.txt", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	var handled error
+	s := New()
+	s.SetErrorHandler(func(c *Context, err error) error {
+		handled = err
+		return defaultErrorHandler(c, err)
+	})
+	s.GET("/dl", func(c *Context) error { return c.Download(root, c.Query("name"), c.Query("as")) })
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		body        string
+		disposition string
+		cause       error
+	}{
+		{"file", "name=report.pdf", StatusOK, "%PDF-1.4 report", `attachment; filename="report.pdf"`, nil},
+		{"nested", "name=docs/a.txt", StatusOK, "a", `attachment; filename="a.txt"`, nil},
+		{"lexically local", "name=docs/../docs/b.txt", StatusOK, "b", `attachment; filename="b.txt"`, nil},
+		{"symlink inside", "name=inside", StatusOK, "a", `attachment; filename="inside"`, nil},
+		{"renamed", "name=report.pdf&as=Bericht für Ärzte.pdf", StatusOK, "%PDF-1.4 report", `attachment; filename="Bericht f_r _rzte.pdf"; filename*=UTF-8''Bericht%20f%C3%BCr%20%C3%84rzte.pdf`, nil},
+		{"parent", "name=../secret.txt", StatusBadRequest, "", "", ErrUnsafePath},
+		{"nested parent", "name=docs/../../secret.txt", StatusBadRequest, "", "", ErrUnsafePath},
+		{"absolute", "name=" + filepath.Join(dir, "secret.txt"), StatusBadRequest, "", "", ErrUnsafePath},
+		{"symlink outside", "name=escape", StatusBadRequest, "", "", ErrUnsafePath},
+		{"missing", "name=missing.txt", StatusNotFound, "", "", nil},
+		{"directory", "name=docs", StatusNotFound, "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled = nil
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetRequestURI("/dl?" + strings.ReplaceAll(tt.query, " ", "%20"))
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d (error %v)", got, tt.status, handled)
+			}
+			if tt.cause != nil && !errors.Is(handled, tt.cause) {
+				t.Errorf("error = %v, want %v", handled, tt.cause)
+			}
+			if tt.status != StatusOK {
+				return
+			}
+			if got := string(ctx.Response.Body()); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+			if got := string(ctx.Response.Header.Peek(HeaderContentDisposition)); got != tt.disposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.disposition)
+			}
+		})
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	tests := []struct {
+		filename    string
+		disposition string
+		contentType string
+	}{
+		{"", "attachment", ""},
+		{"report.pdf", `attachment; filename="report.pdf"`, "application/pdf"},
+		{"../../etc/passwd", `attachment; filename="passwd"`, ""},
+		{`C:\Users\me\notes.txt`, `attachment; filename="notes.txt"`, "text/plain; charset=utf-8"},
+		{"say \"hi\".txt", `attachment; filename="say _hi_.txt"`, "text/plain; charset=utf-8"},
+		{"line\r\nbreak.txt", `attachment; filename="linebreak.txt"`, "text/plain; charset=utf-8"},
+		{"..", "attachment", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			s := New()
+			s.GET("/", func(c *Context) error {
+				c.Attachment(tt.filename)
+				return nil
+			})
+			var ctx fasthttp.RequestCtx
+			ctx.Request.SetRequestURI("/")
+			s.Handler(&ctx)
+
+			if got := string(ctx.Response.Header.Peek(HeaderContentDisposition)); got != tt.disposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.disposition)
+			}
+			if tt.contentType != "" {
+				if got := string(ctx.Response.Header.ContentType()); got != tt.contentType {
+					t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+				}
+			}
+		})
+	}
+}
+
+func TestSendStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		preset      string
+		contentType string
+	}{
+		{"sniffed html", "<html><body>hi</body></html>", "", "text/html; charset=utf-8"},
+		{"sniffed text", "plain words", "", "text/plain; charset=utf-8"},
+		{"explicit", "{}", "application/json", "application/json"},
+		{"empty", "", "", "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.GET("/", func(c *Context) error {
+				if tt.preset != "" {
+					c.ContentType(tt.preset)
+				}
+				return c.SendStream(strings.NewReader(tt.body), -1)
+			})
+			var ctx fasthttp.RequestCtx
+			ctx.Request.SetRequestURI("/")
+			s.Handler(&ctx)
+
+			if got := string(ctx.Response.Header.ContentType()); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := string(ctx.Response.Body()); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
@@ -1,12 +1,220 @@
 package kokoro
 
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+)
+
 type ErrorHandler func(*Context, error) error
 
+// HTTPError is an error carrying the HTTP status and the RFC 9457 problem details
+// sent to the client. Handlers can return it directly:
+//
+//	return &kokoro.HTTPError{Code: 404, Message: "user not found"}
+//
+// Code and Message map to the "status" and "detail" members of the problem document.
+// Err and Internal are never sent to the client; they are kept for logging.
 type HTTPError struct {
 	Code    int
 	Message string
+
+	// Type is a URI reference identifying the problem type. Defaults to "about:blank".
+	Type string
+	// Title is a short summary of the problem type. Defaults to the status text.
+	Title string
+	// Instance is a URI reference identifying this occurrence of the problem.
+	Instance string
+	// Extensions holds additional members rendered alongside the standard ones.
+	Extensions map[string]any
+
+	// Err is the underlying cause, exposed through Unwrap.
+	Err error
+	// Internal holds details for logs and crash reports that must not reach the client.
+	Internal map[string]any
+}
+
+// NewHTTPError creates an HTTPError with the given status code and an optional message.
+// The message defaults to the status text of the code.
+func NewHTTPError(code int, message ...string) *HTTPError {
+	e := &HTTPError{Code: code, Message: fasthttp.StatusMessage(code)}
+	if len(message) > 0 {
+		e.Message = message[0]
+	}
+	return e
 }
 
 func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
 	return e.Message
 }
+
+// Unwrap returns the underlying cause so errors.Is and errors.As can inspect it.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// WithCause sets the underlying cause of the error and returns the error for chaining.
+func (e *HTTPError) WithCause(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+// Problem converts the error into the problem details document sent to the client.
+func (e *HTTPError) Problem() Problem {
+	p := Problem{
+		Type:       e.Type,
+		Title:      e.Title,
+		Status:     e.Code,
+		Detail:     e.Message,
+		Instance:   e.Instance,
+		Extensions: e.Extensions,
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = fasthttp.StatusMessage(e.Code)
+	}
+	if p.Detail == p.Title {
+		p.Detail = ""
+	}
+	return p
+}
+
+// Problem is an RFC 9457 problem details document.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// Map returns the document as a map holding the standard members, omitting empty
+// ones, and the extension members. It is the form used by the JSON and YAML encoders.
+func (p Problem) Map() H {
+	m := make(H, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return m
+}
+
+// problemXMLNamespace is the XML namespace defined for problem details by RFC 9457, Appendix B.
+const problemXMLNamespace = "urn:ietf:rfc:7807"
+
+// MarshalXML renders the document in the XML format of RFC 9457, Appendix B.
+// Extension members are written as child elements in key order. Values other than
+// strings, numbers and booleans, such as maps and slices, are written as JSON text.
+func (p Problem) MarshalXML(enc *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "problem"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: problemXMLNamespace}},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	m := p.Map()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := enc.EncodeElement(xmlValue(m[k]), xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlValue returns v if encoding/xml can render it as element text, or its JSON
+// encoding otherwise.
+func xmlValue(v any) any {
+	if v == nil {
+		return ""
+	}
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// problemOffers lists the media types a problem document can be negotiated into.
+var problemOffers = []string{
+	"application/problem+json",
+	"application/json",
+	"application/problem+xml",
+	"application/xml",
+	"text/xml",
+	"application/x-yaml",
+	"application/yaml",
+}
+
+// SendProblem sends the problem details document with the document's status code.
+// The format is negotiated from the Accept header using the server's encoders:
+// XML and YAML are used when preferred by the client, JSON otherwise, including
+// when the preferred encoder fails, so an error response is always sent.
+func (c *Context) SendProblem(p Problem) error {
+	var (
+		data        []byte
+		err         error
+		contentType string
+	)
+	switch c.Accepts(problemOffers...) {
+	case "application/problem+xml", "application/xml", "text/xml":
+		data, err = c.server.XmlEncoder(p)
+		contentType = "application/problem+xml"
+	case "application/x-yaml", "application/yaml":
+		data, err = c.server.YamlEncoder(p.Map())
+		contentType = "application/x-yaml"
+	}
+	if data == nil || err != nil {
+		data, err = c.server.JsonEncoder(p.Map())
+		contentType = "application/problem+json"
+	}
+	if err != nil {
+		// Drop the extension members, the only ones that may not be encodable.
+		p.Extensions = nil
+		if data, err = c.server.JsonEncoder(p.Map()); err != nil {
+			return err
+		}
+	}
+	c.Status(p.Status)
+	c.ContentType(contentType)
+	c.ctx.SetBody(data)
+	return nil
+}
+
+// defaultErrorHandler renders errors as problem details. *HTTPError values, including
+// wrapped ones, are rendered as-is; other errors are translated by the mappings
+// registered with OnError and OnErrorType, or become a 500 without exposing their message.
+func defaultErrorHandler(c *Context, err error) error {
+	return c.SendProblem(c.server.resolveError(err).Problem())
+}
@@ -0,0 +1,62 @@
+package kokoro
+
+import "errors"
+
+// errorMapping converts a matching domain error into an HTTPError,
+// returning nil when the error does not match.
+type errorMapping func(err error) *HTTPError
+
+// OnError maps errors matching target, as reported by errors.Is, to an HTTPError
+// with the given status code and optional message. The message defaults to the
+// status text, so the domain error's own text is never sent to the client.
+//
+// Example:
+//
+//	s.OnError(sql.ErrNoRows, kokoro.StatusNotFound)
+//	s.OnError(ErrQuotaExceeded, kokoro.StatusTooManyRequests, "quota exceeded")
+func (s *Server) OnError(target error, code int, message ...string) {
+	s.errorMappings = append(s.errorMappings, func(err error) *HTTPError {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return NewHTTPError(code, message...).WithCause(err)
+	})
+}
+
+// OnErrorType maps errors of type T, as found by errors.As, to the HTTPError
+// returned by fn. If fn returns nil, the remaining mappings are consulted.
+//
+// Example:
+//
+//	kokoro.OnErrorType(s, func(e *ValidationError) *kokoro.HTTPError {
+//	    return &kokoro.HTTPError{Code: 422, Message: e.Error(), Extensions: kokoro.H{"field": e.Field}}
+//	})
+func OnErrorType[T error](s *Server, fn func(err T) *HTTPError) {
+	s.errorMappings = append(s.errorMappings, func(err error) *HTTPError {
+		var target T
+		if !errors.As(err, &target) {
+			return nil
+		}
+		e := fn(target)
+		if e != nil && e.Err == nil {
+			e.Err = err
+		}
+		return e
+	})
+}
+
+// resolveError returns the HTTPError describing err: the error itself or one it wraps,
+// the result of the first matching mapping registered with OnError or OnErrorType,
+// or a generic 500 Internal Server Error.
+func (s *Server) resolveError(err error) *HTTPError {
+	var e *HTTPError
+	if errors.As(err, &e) {
+		return e
+	}
+	for _, mapping := range s.errorMappings {
+		if e := mapping(err); e != nil {
+			return e
+		}
+	}
+	return NewHTTPError(StatusInternalServerError).WithCause(err)
+}
@@ -0,0 +1,65 @@
+package kokoro
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// GenerateETag returns an entity tag for body, derived from its length and a
+// 64-bit FNV-1a hash (e.g., `"1f4-9c2b0a5d3e7f1a68"`). A weak tag, prefixed with
+// "W/", only claims semantic equivalence, as defined by RFC 9110, Section 8.8.3.
+func GenerateETag(body []byte, weak bool) string {
+	h := fnv.New64a()
+	_, _ = h.Write(body)
+
+	b := make([]byte, 0, 40)
+	if weak {
+		b = append(b, "W/"...)
+	}
+	b = append(b, '"')
+	b = strconv.AppendUint(b, uint64(len(body)), 16)
+	b = append(b, '-')
+	b = strconv.AppendUint(b, h.Sum64(), 16)
+	b = append(b, '"')
+	return string(b)
+}
+
+// etagMatches reports whether the If-Match or If-None-Match header value matches etag.
+// The strong comparison requires both tags to be strong and identical; the weak
+// comparison ignores the "W/" prefixes (RFC 9110, Section 8.8.3.2).
+func etagMatches(header, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	etagWeak := strings.HasPrefix(etag, "W/")
+	if strong && etagWeak {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+
+	for header != "" {
+		header = strings.TrimLeft(header, " \t,")
+		weak := strings.HasPrefix(header, "W/")
+		rest := strings.TrimPrefix(header, "W/")
+		if !strings.HasPrefix(rest, `"`) {
+			// Not an entity tag; skip to the next list member.
+			_, header, _ = strings.Cut(header, ",")
+			continue
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return false
+		}
+		tag := rest[:end+2]
+		header = rest[end+2:]
+		if tag == opaque && !(strong && weak) {
+			return true
+		}
+	}
+	return false
+}
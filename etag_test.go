@@ -0,0 +1,98 @@
+package kokoro
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestGenerateETag(t *testing.T) {
+	if got, want := GenerateETag([]byte("hello"), false), `"5-a430d84680aabd0b"`; got != want {
+		t.Errorf("GenerateETag = %s, want %s", got, want)
+	}
+	if got, want := GenerateETag([]byte("hello"), true), `W/"5-a430d84680aabd0b"`; got != want {
+		t.Errorf("weak GenerateETag = %s, want %s", got, want)
+	}
+	if GenerateETag([]byte("hello"), false) == GenerateETag([]byte("hellO"), false) {
+		t.Error("different bodies share an ETag")
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		strong bool
+		want   bool
+	}{
+		{`"a"`, `"a"`, false, true},
+		{`"a"`, `"a"`, true, true},
+		{`W/"a"`, `"a"`, false, true},
+		{`W/"a"`, `"a"`, true, false},
+		{`"a"`, `W/"a"`, false, true},
+		{`"a"`, `W/"a"`, true, false},
+		{`"b", "a"`, `"a"`, true, true},
+		{`"b",W/"a"`, `"a"`, false, true},
+		{`"a,b"`, `"a"`, false, false},
+		{`"a,b"`, `"a,b"`, false, true},
+		{`*`, `"a"`, true, true},
+		{` * `, `W/"a"`, false, true},
+		{`*`, ``, false, false},
+		{`"b"`, `"a"`, false, false},
+		{`garbage, "a"`, `"a"`, false, true},
+		{`"unterminated`, `"a"`, false, false},
+		{``, `"a"`, false, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, tt.etag, tt.strong); got != tt.want {
+			t.Errorf("etagMatches(%q, %q, strong %v) = %v, want %v", tt.header, tt.etag, tt.strong, got, tt.want)
+		}
+	}
+}
+
+func TestFresh(t *testing.T) {
+	lastModified := "Wed, 01 May 2024 10:00:00 GMT"
+	tests := []struct {
+		name     string
+		method   string
+		request  [][2]string
+		response [][2]string
+		want     bool
+	}{
+		{"no validators", MethodGet, nil, [][2]string{{HeaderETag, `"a"`}}, false},
+		{"etag match", MethodGet, [][2]string{{HeaderIfNoneMatch, `"a"`}}, [][2]string{{HeaderETag, `"a"`}}, true},
+		{"etag mismatch", MethodGet, [][2]string{{HeaderIfNoneMatch, `"b"`}}, [][2]string{{HeaderETag, `"a"`}}, false},
+		{"no response etag", MethodGet, [][2]string{{HeaderIfNoneMatch, `"a"`}}, nil, false},
+		{"not modified", MethodGet, [][2]string{{HeaderIfModifiedSince, lastModified}}, [][2]string{{HeaderLastModified, lastModified}}, true},
+		{"modified", MethodGet, [][2]string{{HeaderIfModifiedSince, "Tue, 30 Apr 2024 10:00:00 GMT"}}, [][2]string{{HeaderLastModified, lastModified}}, false},
+		{"modified since ignored for POST", MethodPost, [][2]string{{HeaderIfModifiedSince, lastModified}}, [][2]string{{HeaderLastModified, lastModified}}, false},
+		{"invalid date", MethodGet, [][2]string{{HeaderIfModifiedSince, "yesterday"}}, [][2]string{{HeaderLastModified, lastModified}}, false},
+		{"if-none-match first", MethodGet, [][2]string{{HeaderIfNoneMatch, `"b"`}, {HeaderIfModifiedSince, lastModified}}, [][2]string{{HeaderETag, `"a"`}, {HeaderLastModified, lastModified}}, false},
+		{"no-cache", MethodGet, [][2]string{{HeaderIfNoneMatch, `"a"`}, {HeaderCacheControl, "max-age=0, No-Cache"}}, [][2]string{{HeaderETag, `"a"`}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestContext(tt.method, tt.request)
+			for _, h := range tt.response {
+				c.ctx.Response.Header.Set(h[0], h[1])
+			}
+			if got := c.Fresh(); got != tt.want {
+				t.Errorf("Fresh() = %v, want %v", got, tt.want)
+			}
+			if got := c.Stale(); got == tt.want {
+				t.Errorf("Stale() = %v, want %v", got, !tt.want)
+			}
+		})
+	}
+}
+
+// newTestContext returns a Context for a request to "/" with the given method and headers.
+func newTestContext(method string, headers [][2]string) *Context {
+	c := acquireContext(&fasthttp.RequestCtx{}, New())
+	c.ctx.Request.Header.SetMethod(method)
+	c.ctx.Request.SetRequestURI("/")
+	for _, h := range headers {
+		c.ctx.Request.Header.Set(h[0], h[1])
+	}
+	return c
+}
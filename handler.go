@@ -45,3 +45,21 @@ type middlewareFunc func(next HandlerFunc) HandlerFunc
 //	    return next(ctx)
 //	}
 type NextMiddleware func(ctx *Context, next HandlerFunc) error
+
+// WithErrorHandler returns a route middleware that handles errors of a single route
+// with h, before the error handlers of the route's groups and the Server.
+// If h returns an error, that error is passed on to those handlers.
+//
+// Example:
+//
+//	s.GET("/report", report, kokoro.WithErrorHandler(func(c *kokoro.Context, err error) error {
+//	    return c.Status(500).SendText("report unavailable")
+//	}))
+func WithErrorHandler(h ErrorHandler) NextMiddleware {
+	return func(ctx *Context, next HandlerFunc) error {
+		if err := next(ctx); err != nil {
+			return h(ctx, err)
+		}
+		return nil
+	}
+}
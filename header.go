@@ -219,6 +219,15 @@ const (
 	// HeaderForwardedProto indicates the originating protocol (HTTP or HTTPS).
 	HeaderForwardedProto = "X-Forwarded-Proto"
 
+	// HeaderXRealIP carries the client IP address as seen by a reverse proxy such as nginx.
+	HeaderXRealIP = "X-Real-IP"
+
+	// HeaderCFConnectingIP carries the client IP address as seen by Cloudflare.
+	HeaderCFConnectingIP = "CF-Connecting-IP"
+
+	// HeaderTrueClientIP carries the client IP address as seen by Akamai or Cloudflare Enterprise.
+	HeaderTrueClientIP = "True-Client-IP"
+
 	// HeaderHTTP2Settings is used in HTTP/2 to carry connection-specific settings.
 	HeaderHTTP2Settings = "HTTP2-Settings"
 
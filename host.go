@@ -0,0 +1,169 @@
+package kokoro
+
+import (
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// hostRoute binds a parsed host pattern to the Router that serves requests for it.
+type hostRoute struct {
+	pattern string
+	labels  []string // Lower-cased host labels; "{name}" captures a param, "*" matches any label.
+	router  *Router
+}
+
+// parseHostPattern splits a host pattern such as "{tenant}.api.example.com"
+// into its labels, dropping any port and normalizing case.
+func parseHostPattern(pattern string) []string {
+	if host, _, err := net.SplitHostPort(pattern); err == nil {
+		pattern = host
+	}
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(pattern, ".")), ".")
+	for _, label := range labels {
+		if label == "" {
+			panic("kokoro: invalid host pattern " + pattern)
+		}
+		if isHostParam(label) && len(label) == 2 {
+			panic("kokoro: empty param name in host pattern " + pattern)
+		}
+	}
+	return labels
+}
+
+// isHostParam reports whether the label is a "{name}" placeholder.
+func isHostParam(label string) bool {
+	return len(label) >= 2 && label[0] == '{' && label[len(label)-1] == '}'
+}
+
+// match reports whether the given host (without port) satisfies the pattern.
+func (h *hostRoute) match(labels []string) bool {
+	if len(labels) != len(h.labels) {
+		return false
+	}
+	for i, label := range h.labels {
+		if label == "*" || isHostParam(label) {
+			continue
+		}
+		if !strings.EqualFold(label, labels[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// bind stores the values captured by the pattern's placeholders as route params,
+// making them available through Context.Param.
+func (h *hostRoute) bind(fctx *fasthttp.RequestCtx, labels []string) {
+	for i, label := range h.labels {
+		if isHostParam(label) {
+			fctx.SetUserValue(label[1:len(label)-1], labels[i])
+		}
+	}
+}
+
+// Host creates a Router whose routes only match requests for the given host pattern.
+//
+// Each dot-separated label of the pattern is either a literal, "*" to match any
+// single label, or a "{name}" placeholder whose value is exposed as a route param:
+//
+//	api := s.Host("{tenant}.api.example.com")
+//	api.GET("/users", func(c *kokoro.Context) error {
+//	    return c.SendText("tenant: " + c.Param("tenant"))
+//	})
+//
+// Hosts are matched case-insensitively, ignoring the port, in registration order.
+// Requests whose host matches no pattern, or whose path has no route on the
+// matched host, fall back to the routes registered on the Server itself.
+// The returned Router inherits the prefix, middlewares and error handlers of r.
+func (r *Router) Host(pattern string) *Router {
+	if r.server == nil {
+		panic("kokoro: Host requires a Router created by kokoro.New")
+	}
+	return r.server.host(pattern, r)
+}
+
+// host returns a Router bound to the pattern, creating the underlying router
+// the first time the pattern is seen.
+func (s *Server) host(pattern string, parent *Router) *Router {
+	labels := parseHostPattern(pattern)
+	normalized := strings.Join(labels, ".")
+
+	for _, h := range s.hosts {
+		if h.pattern == normalized {
+			return &Router{r: h.router.r, globalMiddlewares: parent.globalMiddlewares, basePath: parent.basePath, server: s, parent: parent}
+		}
+	}
+
+	hr := &Router{r: router.New(), globalMiddlewares: parent.globalMiddlewares, basePath: parent.basePath, server: s, parent: parent}
+	hr.r.RedirectTrailingSlash = false
+	hr.r.RedirectFixedPath = false
+	hr.r.NotFound = func(fctx *fasthttp.RequestCtx) {
+		if !s.fixPath(hr.r, fctx) {
+			s.dispatch(s.r, fctx)
+		}
+	}
+	hr.r.MethodNotAllowed = func(fctx *fasthttp.RequestCtx) {
+		// A route of the Server itself may accept the method for this path.
+		path := s.BytesToString(fctx.Request.URI().PathOriginal())
+		if h := s.lookup(s.r, string(fctx.Method()), path, fctx); h != nil {
+			fctx.Response.Header.Del(HeaderAllow)
+			h(fctx)
+			return
+		}
+		methods := strings.Split(string(fctx.Response.Header.Peek(HeaderAllow)), ", ")
+		for _, method := range strings.Split(allowedMethods(s.r, path), ", ") {
+			if !slices.Contains(methods, method) {
+				methods = append(methods, method)
+			}
+		}
+		slices.Sort(methods)
+		fctx.Response.Header.Set(HeaderAllow, strings.Join(methods, ", "))
+		s.serve(fctx, s.errorGroup(hr, fctx), "", methodNotAllowed)
+	}
+	hr.r.GlobalOPTIONS = s.r.GlobalOPTIONS
+	s.hosts = append(s.hosts, &hostRoute{pattern: normalized, labels: labels, router: hr})
+	return hr
+}
+
+// hasRoute reports whether a route of the Server, or of one of its host groups,
+// was registered with pattern.
+func (s *Server) hasRoute(pattern string) bool {
+	path := expandParamConstraints(pattern)
+	routers := []*router.Router{s.r}
+	for _, h := range s.hosts {
+		routers = append(routers, h.router.r)
+	}
+	for _, r := range routers {
+		for _, paths := range r.List() {
+			if slices.Contains(paths, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Handler dispatches the request to the first host group matching the request
+// host, or to the Server's own routes when none matches.
+// It is the fasthttp.RequestHandler used by Listen.
+func (s *Server) Handler(fctx *fasthttp.RequestCtx) {
+	if len(s.hosts) > 0 {
+		host := string(fctx.Host())
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+		for _, h := range s.hosts {
+			if h.match(labels) {
+				h.bind(fctx, labels)
+				s.dispatch(h.router.r, fctx)
+				return
+			}
+		}
+	}
+	s.dispatch(s.r, fctx)
+}
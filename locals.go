@@ -0,0 +1,112 @@
+package kokoro
+
+import (
+	"log/slog"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SetLocal stores a value scoped to the current request, e.g. for passing data
+// from a middleware to the handler. Use an unexported key type to avoid collisions
+// with route params and other packages.
+func (c *Context) SetLocal(key, value any) {
+	c.ctx.SetUserValue(key, value)
+}
+
+// Local retrieves a value stored with SetLocal, or nil if the key is not set.
+func (c *Context) Local(key any) any {
+	return c.ctx.UserValue(key)
+}
+
+// csrfTokenKey is the Context local holding the CSRF token of the request.
+type csrfTokenKey struct{}
+
+// CSRFToken returns the CSRF token issued for the request by a CSRF middleware,
+// for embedding in forms and templates. It is empty if no middleware set one.
+func (c *Context) CSRFToken() string {
+	token, _ := c.Local(csrfTokenKey{}).(string)
+	return token
+}
+
+// SetCSRFToken exposes the CSRF token issued for the request through CSRFToken.
+// It is meant to be called by CSRF middlewares.
+func (c *Context) SetCSRFToken(token string) {
+	c.SetLocal(csrfTokenKey{}, token)
+}
+
+// Logger returns the logger scoped to the current request. It defaults to the
+// Server's Logger, or slog.Default() if none is configured.
+func (c *Context) Logger() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	if c.server.Logger != nil {
+		return c.server.Logger
+	}
+	return slog.Default()
+}
+
+// SetLogger replaces the logger scoped to the current request, e.g. with one
+// carrying request attributes:
+//
+//	c.SetLogger(c.Logger().With("user_id", userID))
+func (c *Context) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
+// propagatedHeader is a header registered with PropagateHeader or KeepHeader.
+type propagatedHeader struct {
+	key, value string
+	outbound   bool // Copied onto outbound requests made with Do.
+}
+
+// PropagateHeader registers a header copied onto every outbound request made
+// through Do while handling the current request, such as a request ID or tracing header.
+// It is also kept on the response when it is reset to render a recovered panic.
+func (c *Context) PropagateHeader(key, value string) {
+	c.registerHeader(key, value, true)
+}
+
+// KeepHeader registers a response header kept when the response is reset to render
+// a recovered panic, such as the CORS headers browsers need to read the error.
+// Unlike PropagateHeader, it is not copied onto outbound requests.
+func (c *Context) KeepHeader(key, value string) {
+	c.registerHeader(key, value, false)
+}
+
+// registerHeader adds or updates a header of the propagated list.
+func (c *Context) registerHeader(key, value string, outbound bool) {
+	for i := range c.propagated {
+		if h := &c.propagated[i]; h.key == key {
+			h.value = value
+			h.outbound = h.outbound || outbound
+			return
+		}
+	}
+	c.propagated = append(c.propagated, propagatedHeader{key: key, value: value, outbound: outbound})
+}
+
+// Do performs an outbound request with the Server's Client, after copying the
+// headers registered with PropagateHeader onto it.
+//
+// Example:
+//
+//	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+//	defer fasthttp.ReleaseRequest(req)
+//	defer fasthttp.ReleaseResponse(resp)
+//	req.SetRequestURI("http://inventory/items")
+//	if err := c.Do(req, resp); err != nil {
+//	    return err
+//	}
+func (c *Context) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	for _, h := range c.propagated {
+		if h.outbound {
+			req.Header.Set(h.key, h.value)
+		}
+	}
+	client := c.server.Client
+	if client == nil {
+		return fasthttp.Do(req, resp)
+	}
+	return client.Do(req, resp)
+}
@@ -0,0 +1,186 @@
+// Package compress provides a response compression middleware for Kokoro supporting
+// gzip, deflate, brotli and zstd.
+package compress
+
+import (
+	"strings"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/valyala/fasthttp"
+)
+
+// Level selects the trade-off between compression speed and ratio.
+type Level int
+
+const (
+	LevelDefault         Level = iota // A balanced level for each encoding.
+	LevelBestSpeed                    // The fastest level of each encoding.
+	LevelBestCompression              // The smallest output of each encoding.
+)
+
+// Supported content encodings.
+const (
+	Brotli  = "br"
+	Zstd    = "zstd"
+	Gzip    = "gzip"
+	Deflate = "deflate"
+)
+
+// DefaultSkipContentTypes lists media type prefixes of content that is already
+// compressed and is never compressed again.
+var DefaultSkipContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif", "image/heic", "image/heif",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-bzip2", "application/x-xz", "application/x-7z-compressed",
+	"application/x-rar-compressed", "application/vnd.rar",
+}
+
+// Config defines the configuration for the compression middleware.
+type Config struct {
+	// Level is the compression level. Defaults to LevelDefault.
+	Level Level
+
+	// Encodings lists the offered encodings in order of preference, used when the
+	// client accepts several of them equally. Defaults to br, zstd, gzip and deflate.
+	Encodings []string
+
+	// MinLength is the minimum size, in bytes, of a body worth compressing.
+	// Streamed bodies of unknown size are always compressed. Defaults to 1024.
+	MinLength int
+
+	// SkipContentTypes lists media type prefixes that are never compressed.
+	// Defaults to DefaultSkipContentTypes.
+	SkipContentTypes []string
+
+	// Skip reports whether the request's response must not be compressed.
+	Skip func(c *kokoro.Context) bool
+}
+
+// New creates a response compression middleware.
+//
+// The encoding is negotiated through Context.AcceptsEncoding. Responses are left
+// untouched when they are smaller than MinLength, already encoded, partial, of a
+// skipped content type, marked with "Cache-Control: no-transform", or when
+// compression would not make them smaller. Accept-Encoding is added to Vary
+// whenever the response could have been compressed, and the ETag of responses
+// negotiated for an encoding is made weak, compressed or not. 304 Not Modified
+// responses get the same Vary and ETag as the 200 they stand for, as required by
+// RFC 9110, Section 15.4.5.
+//
+// Streamed bodies, set with SetBodyStream, are compressed on the fly as they are
+// written to the client. Errors returned by the next handler are passed on
+// unchanged and their responses are not compressed.
+//
+// Example:
+//
+//	s.Use(compress.New(compress.Config{Level: compress.LevelBestSpeed}))
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{Brotli, Zstd, Gzip, Deflate}
+	}
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 1024
+	}
+	if cfg.SkipContentTypes == nil {
+		cfg.SkipContentTypes = DefaultSkipContentTypes
+	}
+
+	encoders := make(map[string]*encoder, len(cfg.Encodings))
+	for _, name := range cfg.Encodings {
+		e := newEncoder(name, cfg.Level)
+		if e == nil {
+			panic("compress: unsupported encoding " + name)
+		}
+		encoders[name] = e
+	}
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			return next(c)
+		}
+		if err := next(c); err != nil {
+			return err
+		}
+
+		resp := &c.RequestCtx().Response
+		// A 304 has no body to compress, but stands for a 200 that would have been.
+		notModified := resp.StatusCode() == fasthttp.StatusNotModified
+		if !notModified && !compressible(resp, cfg.SkipContentTypes) || noTransform(resp) {
+			return nil
+		}
+		c.Vary(kokoro.HeaderAcceptEncoding)
+
+		e := encoders[c.AcceptsEncoding(cfg.Encodings...)]
+		if e == nil {
+			return nil
+		}
+		// The ETag is weakened even when the body ends up too small to compress,
+		// since a 304 cannot tell whether it would have been.
+		weakenETag(resp)
+		if notModified {
+			return nil
+		}
+
+		if resp.IsBodyStream() {
+			if n := resp.Header.ContentLength(); n >= 0 && n < cfg.MinLength {
+				return nil
+			}
+			e.stream(c.RequestCtx())
+			if len(resp.Header.ContentEncoding()) == 0 {
+				return nil
+			}
+		} else {
+			body := resp.Body()
+			if len(body) < cfg.MinLength {
+				return nil
+			}
+			compressed := e.encode(body)
+			if compressed == nil {
+				return nil
+			}
+			resp.SetBodyRaw(compressed)
+			resp.Header.Set(kokoro.HeaderContentEncoding, e.name)
+		}
+		return nil
+	}
+}
+
+// compressible reports whether the response may be compressed.
+func compressible(resp *fasthttp.Response, skipTypes []string) bool {
+	switch status := resp.StatusCode(); {
+	case status < 200, status == fasthttp.StatusNoContent, status == fasthttp.StatusNotModified,
+		status == fasthttp.StatusPartialContent:
+		return false
+	}
+	h := &resp.Header
+	if len(h.ContentEncoding()) > 0 || len(h.Peek(kokoro.HeaderContentRange)) > 0 {
+		return false
+	}
+	contentType := strings.ToLower(string(h.ContentType()))
+	for _, prefix := range skipTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// noTransform reports whether the response forbids intermediaries, compression
+// included, from transforming it.
+func noTransform(resp *fasthttp.Response) bool {
+	return strings.Contains(strings.ToLower(string(resp.Header.Peek(kokoro.HeaderCacheControl))), "no-transform")
+}
+
+// weakenETag turns a strong ETag into a weak one, since the compressed
+// representation is no longer byte-for-byte identical to the original.
+func weakenETag(resp *fasthttp.Response) {
+	etag := string(resp.Header.Peek(kokoro.HeaderETag))
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		resp.Header.Set(kokoro.HeaderETag, "W/"+etag)
+	}
+}
@@ -0,0 +1,203 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/Abhishek2010dev/kokoro/middleware/etag"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/valyala/fasthttp"
+)
+
+var text = strings.Repeat("kokoro compresses repetitive text well. ", 100)
+
+func serve(s *kokoro.Server, path string, headers ...string) *fasthttp.Response {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI(path)
+	for i := 0; i+1 < len(headers); i += 2 {
+		ctx.Request.Header.Set(headers[i], headers[i+1])
+	}
+	s.Handler(&ctx)
+	resp := &fasthttp.Response{}
+	ctx.Response.CopyTo(resp)
+	if ctx.Response.IsBodyStream() {
+		// Streamed bodies are encoded as they are written to the client.
+		var buf bytes.Buffer
+		_ = ctx.Response.BodyWriteTo(&buf)
+		resp.SetBodyRaw(buf.Bytes())
+	}
+	return resp
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "":
+		return string(body)
+	case Gzip:
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case Deflate:
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case Zstd:
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(bytes.NewReader(body))
+		r = d
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", encoding, err)
+	}
+	return string(data)
+}
+
+func TestCompress(t *testing.T) {
+	s := kokoro.New()
+	s.Use(New())
+	s.GET("/text", func(c *kokoro.Context) error { return c.SendText(text) })
+	s.GET("/small", func(c *kokoro.Context) error { return c.SendText("tiny") })
+	s.GET("/png", func(c *kokoro.Context) error {
+		c.RequestCtx().SetBodyString(text)
+		c.ContentType("image/png")
+		return nil
+	})
+	s.GET("/no-transform", func(c *kokoro.Context) error {
+		c.SetHeader(kokoro.HeaderCacheControl, "no-transform")
+		return c.SendText(text)
+	})
+	s.GET("/encoded", func(c *kokoro.Context) error {
+		c.SetHeader(kokoro.HeaderContentEncoding, "gzip")
+		return c.SendText(text)
+	})
+	s.GET("/partial", func(c *kokoro.Context) error {
+		return c.Status(kokoro.StatusPartialContent).SendText(text)
+	})
+	s.GET("/stream", func(c *kokoro.Context) error {
+		return c.SendStream(strings.NewReader(text), -1)
+	})
+	s.GET("/etag", func(c *kokoro.Context) error {
+		c.SetHeader(kokoro.HeaderETag, `"v1"`)
+		return c.SendText(text)
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+		vary     bool
+		etag     string
+	}{
+		{"gzip", "/text", "gzip", Gzip, true, ""},
+		{"deflate", "/text", "deflate", Deflate, true, ""},
+		{"brotli", "/text", "br", Brotli, true, ""},
+		{"zstd", "/text", "zstd", Zstd, true, ""},
+		{"server preference", "/text", "gzip, br, zstd", Brotli, true, ""},
+		{"client preference", "/text", "br;q=0.5, gzip", Gzip, true, ""},
+		{"wildcard", "/text", "*", Brotli, true, ""},
+		{"refused", "/text", "gzip;q=0", "", true, ""},
+		{"unsupported", "/text", "compress", "", true, ""},
+		{"identity only", "/text", "identity", "", true, ""},
+		{"no header", "/text", "", "", true, ""},
+		{"too small", "/small", "gzip", "", true, ""},
+		{"skipped type", "/png", "gzip", "", false, ""},
+		{"no-transform", "/no-transform", "gzip", "", false, ""},
+		{"already encoded", "/encoded", "br", Gzip, false, ""},
+		{"partial", "/partial", "gzip", "", false, ""},
+		{"stream", "/stream", "gzip", Gzip, true, ""},
+		{"weak etag", "/etag", "gzip", Gzip, true, `W/"v1"`},
+		{"strong etag", "/etag", "identity", "", true, `"v1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(s, tt.path, kokoro.HeaderAcceptEncoding, tt.accept)
+
+			if got := string(resp.Header.ContentEncoding()); got != tt.encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if tt.path == "/text" || tt.path == "/stream" {
+				if got := decode(t, tt.encoding, resp.Body()); got != text {
+					t.Errorf("decoded body differs from the original")
+				}
+			}
+			if got := strings.Contains(string(resp.Header.Peek(kokoro.HeaderVary)), kokoro.HeaderAcceptEncoding); got != tt.vary {
+				t.Errorf("Vary contains Accept-Encoding = %v, want %v", got, tt.vary)
+			}
+			if tt.etag != "" {
+				if got := string(resp.Header.Peek(kokoro.HeaderETag)); got != tt.etag {
+					t.Errorf("ETag = %q, want %q", got, tt.etag)
+				}
+			}
+		})
+	}
+}
+
+func TestCompressErrorPassedOn(t *testing.T) {
+	errBoom := errors.New("boom")
+	var handled error
+	s := kokoro.New()
+	s.SetErrorHandler(func(c *kokoro.Context, err error) error {
+		handled = err
+		return c.Status(kokoro.StatusInternalServerError).SendText(text)
+	})
+	s.Use(New())
+	s.GET("/", func(c *kokoro.Context) error { return errBoom })
+
+	resp := serve(s, "/", kokoro.HeaderAcceptEncoding, "gzip")
+	if !errors.Is(handled, errBoom) {
+		t.Fatalf("error = %v, want %v", handled, errBoom)
+	}
+	if got := string(resp.Header.ContentEncoding()); got != "" {
+		t.Errorf("Content-Encoding = %q, want none", got)
+	}
+}
+
+// TestCompressNotModified checks that a 304 carries the ETag and Vary of the 200
+// it stands for, as RFC 9110, Section 15.4.5 requires.
+func TestCompressNotModified(t *testing.T) {
+	s := kokoro.New()
+	s.Use(New(), etag.New())
+	s.GET("/text", func(c *kokoro.Context) error { return c.SendText(text) })
+	s.GET("/small", func(c *kokoro.Context) error { return c.SendText("tiny") })
+
+	for _, path := range []string{"/text", "/small"} {
+		for _, accept := range []string{"gzip", "identity"} {
+			t.Run(path+" "+accept, func(t *testing.T) {
+				ok := serve(s, path, kokoro.HeaderAcceptEncoding, accept)
+				if ok.StatusCode() != kokoro.StatusOK {
+					t.Fatalf("status = %d, want 200", ok.StatusCode())
+				}
+				tag := string(ok.Header.Peek(kokoro.HeaderETag))
+				if accept == "gzip" && !strings.HasPrefix(tag, "W/") {
+					t.Errorf("ETag = %q, want a weak tag", tag)
+				}
+
+				notModified := serve(s, path, kokoro.HeaderAcceptEncoding, accept, kokoro.HeaderIfNoneMatch, tag)
+				if notModified.StatusCode() != kokoro.StatusNotModified {
+					t.Fatalf("status = %d, want 304", notModified.StatusCode())
+				}
+				for _, header := range []string{kokoro.HeaderETag, kokoro.HeaderVary} {
+					if got, want := string(notModified.Header.Peek(header)), string(ok.Header.Peek(header)); got != want {
+						t.Errorf("304 %s = %q, want %q as on the 200", header, got, want)
+					}
+				}
+				if len(notModified.Body()) != 0 || len(notModified.Header.ContentEncoding()) != 0 {
+					t.Errorf("304 has a body or Content-Encoding")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/valyala/fasthttp"
+)
+
+// resetWriter is a compressing writer that can be reused for another destination.
+type resetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// encoder compresses bodies with one encoding at one level.
+type encoder struct {
+	name    string
+	writers sync.Pool
+
+	// Levels passed to fasthttp when compressing streamed bodies.
+	brotliLevel int
+	otherLevel  int
+}
+
+// newEncoder returns the encoder of the named encoding, or nil if it is unsupported.
+func newEncoder(name string, level Level) *encoder {
+	if level < LevelDefault || level > LevelBestCompression {
+		level = LevelDefault
+	}
+	e := &encoder{name: name}
+	switch name {
+	case Gzip, Deflate:
+		flateLevel := [...]int{gzip.DefaultCompression, gzip.BestSpeed, gzip.BestCompression}[level]
+		e.otherLevel = [...]int{fasthttp.CompressDefaultCompression, fasthttp.CompressBestSpeed, fasthttp.CompressBestCompression}[level]
+		if name == Gzip {
+			e.writers.New = func() any {
+				w, _ := gzip.NewWriterLevel(nil, flateLevel)
+				return w
+			}
+		} else {
+			// HTTP's deflate encoding is the zlib format, as defined by RFC 9110, Section 8.4.1.2.
+			e.writers.New = func() any {
+				w, _ := zlib.NewWriterLevel(nil, flateLevel)
+				return w
+			}
+		}
+	case Brotli:
+		// Brotli's own default level is too slow for dynamic responses.
+		e.brotliLevel = [...]int{4, brotli.BestSpeed, brotli.BestCompression}[level]
+		e.writers.New = func() any {
+			return brotli.NewWriterLevel(nil, e.brotliLevel)
+		}
+	case Zstd:
+		zstdLevel := [...]zstd.EncoderLevel{zstd.SpeedDefault, zstd.SpeedFastest, zstd.SpeedBestCompression}[level]
+		e.otherLevel = [...]int{fasthttp.CompressZstdDefault, fasthttp.CompressZstdBestSpeed, fasthttp.CompressZstdBestCompression}[level]
+		e.writers.New = func() any {
+			w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel), zstd.WithEncoderConcurrency(1))
+			return w
+		}
+	default:
+		return nil
+	}
+	return e
+}
+
+// encode returns the compressed body, or nil if compression does not make it smaller.
+func (e *encoder) encode(body []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(body) / 2)
+
+	w := e.writers.Get().(resetWriter)
+	defer e.writers.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil
+	}
+	if err := w.Close(); err != nil {
+		return nil
+	}
+	if buf.Len() >= len(body) {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// stream compresses the streamed response body as it is written to the client.
+// fasthttp is relied upon because it can wrap the body stream in place, whereas
+// replacing it through SetBodyStream would close the original stream. The request's
+// Accept-Encoding is narrowed to the negotiated encoding while it does so.
+func (e *encoder) stream(fctx *fasthttp.RequestCtx) {
+	h := &fctx.Request.Header
+	accept := string(h.Peek(kokoro.HeaderAcceptEncoding))
+	h.Set(kokoro.HeaderAcceptEncoding, e.name)
+	fasthttp.CompressHandlerBrotliLevel(func(*fasthttp.RequestCtx) {}, e.brotliLevel, e.otherLevel)(fctx)
+	h.Set(kokoro.HeaderAcceptEncoding, accept)
+}
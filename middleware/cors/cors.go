@@ -0,0 +1,184 @@
+// Package cors provides a Cross-Origin Resource Sharing middleware for Kokoro.
+package cors
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Abhishek2010dev/kokoro"
+)
+
+// Config defines the configuration for the CORS middleware.
+type Config struct {
+	// AllowOrigins lists the origins allowed to make cross-origin requests.
+	// Entries are exact origins ("https://app.example.com"), wildcard subdomains
+	// ("https://*.example.com") or "*" for any origin, which cannot be combined
+	// with AllowCredentials.
+	AllowOrigins []string
+
+	// AllowOriginPatterns lists regular expressions matched against the whole origin,
+	// as if anchored with ^ and $.
+	AllowOriginPatterns []*regexp.Regexp
+
+	// AllowOriginFunc reports whether an origin is allowed, for rules the other
+	// options cannot express.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods lists the methods allowed in preflight requests.
+	// Defaults to GET, HEAD, PUT, PATCH, POST and DELETE.
+	AllowMethods []string
+
+	// AllowHeaders lists the request headers allowed in preflight requests.
+	// When empty, the headers requested by the preflight are allowed.
+	AllowHeaders []string
+
+	// ExposeHeaders lists the response headers readable by the client.
+	ExposeHeaders []string
+
+	// AllowCredentials allows requests with cookies and HTTP authentication.
+	// The requesting origin is then echoed instead of "*".
+	AllowCredentials bool
+
+	// MaxAge is how long, in seconds, the preflight response may be cached.
+	// Zero omits the header; a negative value disables caching.
+	MaxAge int
+}
+
+// New creates a CORS middleware. It panics if AllowOrigins contains "*" while
+// AllowCredentials is set, which would let any site read credentialed responses.
+//
+// Preflight requests (OPTIONS with an Access-Control-Request-Method header) are
+// answered with 204 without calling the next handler. Since Kokoro answers OPTIONS
+// through the middlewares of the matching routes, this works for every route
+// registered through a Router using the middleware, without registering OPTIONS routes.
+//
+// Example:
+//
+//	s.Use(cors.New(cors.Config{
+//	    AllowOrigins:     []string{"https://app.example.com", "https://*.example.dev"},
+//	    AllowCredentials: true,
+//	    MaxAge:           600,
+//	}))
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = []string{
+			kokoro.MethodGet, kokoro.MethodHead, kokoro.MethodPut,
+			kokoro.MethodPatch, kokoro.MethodPost, kokoro.MethodDelete,
+		}
+	}
+
+	allowAll := false
+	exact := make(map[string]struct{})
+	var wildcards [][2]string // scheme+"://" and "."+domain suffix
+	for _, o := range cfg.AllowOrigins {
+		o = strings.ToLower(strings.TrimRight(o, "/"))
+		switch {
+		case o == "*":
+			allowAll = true
+		case strings.Contains(o, "://*."):
+			scheme, domain, _ := strings.Cut(o, "://*")
+			wildcards = append(wildcards, [2]string{scheme + "://", domain})
+		default:
+			exact[o] = struct{}{}
+		}
+	}
+	if allowAll && cfg.AllowCredentials {
+		panic(`cors: AllowOrigins "*" cannot be combined with AllowCredentials`)
+	}
+
+	// Anchor the patterns so they cannot match a prefix of a longer, attacker-owned origin.
+	patterns := make([]*regexp.Regexp, len(cfg.AllowOriginPatterns))
+	for i, re := range cfg.AllowOriginPatterns {
+		patterns[i] = regexp.MustCompile(`^(?:` + re.String() + `)$`)
+	}
+
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		lower := strings.ToLower(origin)
+		if _, ok := exact[lower]; ok {
+			return true
+		}
+		for _, w := range wildcards {
+			if strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) && len(lower) > len(w[0])+len(w[1]) {
+				return true
+			}
+		}
+		for _, re := range patterns {
+			if re.MatchString(origin) {
+				return true
+			}
+		}
+		return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin)
+	}
+
+	allowMethods := strings.Join(cfg.AllowMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAge > 0 {
+		maxAge = strconv.Itoa(cfg.MaxAge)
+	} else if cfg.MaxAge < 0 {
+		maxAge = "0"
+	}
+
+	// The response depends on the Origin header unless every origin gets "*".
+	varyOrigin := !allowAll
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		preflight := c.Method() == kokoro.MethodOptions && c.Header(kokoro.HeaderAccessControlRequestMethod) != ""
+		if varyOrigin {
+			c.Vary(kokoro.HeaderOrigin)
+		}
+		if preflight {
+			c.Vary(kokoro.HeaderAccessControlRequestMethod, kokoro.HeaderAccessControlRequestHeaders)
+		}
+		// The headers set here are kept should a panic reset the response, so
+		// browsers can still read the error.
+		if vary := c.RequestCtx().Response.Header.Peek(kokoro.HeaderVary); len(vary) > 0 {
+			c.KeepHeader(kokoro.HeaderVary, string(vary))
+		}
+		set := func(key, value string) {
+			c.SetHeader(key, value)
+			c.KeepHeader(key, value)
+		}
+
+		origin := c.Header(kokoro.HeaderOrigin)
+		if origin == "" || !allowed(origin) {
+			return next(c)
+		}
+
+		if allowAll {
+			set(kokoro.HeaderAccessControlAllowOrigin, "*")
+		} else {
+			set(kokoro.HeaderAccessControlAllowOrigin, origin)
+		}
+		if cfg.AllowCredentials {
+			set(kokoro.HeaderAccessControlAllowCredentials, "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				set(kokoro.HeaderAccessControlExposeHeaders, exposeHeaders)
+			}
+			return next(c)
+		}
+
+		c.SetHeader(kokoro.HeaderAccessControlAllowMethods, allowMethods)
+		if allowHeaders != "" {
+			c.SetHeader(kokoro.HeaderAccessControlAllowHeaders, allowHeaders)
+		} else if requested := c.Header(kokoro.HeaderAccessControlRequestHeaders); requested != "" {
+			c.SetHeader(kokoro.HeaderAccessControlAllowHeaders, requested)
+		}
+		if maxAge != "" {
+			c.SetHeader(kokoro.HeaderAccessControlMaxAge, maxAge)
+		}
+		return c.SendStatusCode(kokoro.StatusNoContent)
+	}
+}
@@ -0,0 +1,122 @@
+package cors
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/valyala/fasthttp"
+)
+
+func serve(s *kokoro.Server, method, path, origin string) *fasthttp.Response {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(path)
+	if origin != "" {
+		ctx.Request.Header.Set(kokoro.HeaderOrigin, origin)
+	}
+	if method == kokoro.MethodOptions {
+		ctx.Request.Header.Set(kokoro.HeaderAccessControlRequestMethod, kokoro.MethodPost)
+	}
+	s.Handler(&ctx)
+	resp := &fasthttp.Response{}
+	ctx.Response.CopyTo(resp)
+	return resp
+}
+
+func TestOriginMatching(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		origin string
+		want   string
+	}{
+		{"exact", Config{AllowOrigins: []string{"https://app.example.com"}}, "https://app.example.com", "https://app.example.com"},
+		{"exact case-insensitive", Config{AllowOrigins: []string{"https://App.Example.com/"}}, "https://app.example.com", "https://app.example.com"},
+		{"exact other", Config{AllowOrigins: []string{"https://app.example.com"}}, "https://app.example.org", ""},
+		{"exact suffix", Config{AllowOrigins: []string{"https://app.example.com"}}, "https://app.example.com.evil.io", ""},
+		{"wildcard subdomain", Config{AllowOrigins: []string{"https://*.example.com"}}, "https://a.b.example.com", "https://a.b.example.com"},
+		{"wildcard apex", Config{AllowOrigins: []string{"https://*.example.com"}}, "https://example.com", ""},
+		{"wildcard lookalike", Config{AllowOrigins: []string{"https://*.example.com"}}, "https://evilexample.com", ""},
+		{"wildcard scheme", Config{AllowOrigins: []string{"https://*.example.com"}}, "http://a.example.com", ""},
+		{"any", Config{AllowOrigins: []string{"*"}}, "https://anything.io", "*"},
+		{"pattern", Config{AllowOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`https://app\.example\.com`)}}, "https://app.example.com", "https://app.example.com"},
+		{"pattern suffix", Config{AllowOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`https://app\.example\.com`)}}, "https://app.example.com.evil.io", ""},
+		{"pattern prefix", Config{AllowOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`app\.example\.com`)}}, "https://app.example.com", ""},
+		{"pattern alternation", Config{AllowOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`https://a\.io|https://b\.io`)}}, "https://a.io.evil.io", ""},
+		{"func", Config{AllowOriginFunc: func(o string) bool { return o == "https://f.io" }}, "https://f.io", "https://f.io"},
+		{"credentials", Config{AllowOrigins: []string{"https://app.example.com"}, AllowCredentials: true}, "https://app.example.com", "https://app.example.com"},
+		{"no origin", Config{AllowOrigins: []string{"*"}}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := kokoro.New()
+			s.Use(New(tt.config))
+			s.GET("/users/{id}", func(c *kokoro.Context) error { return c.SendText("ok") })
+			s.POST("/users/{id}", func(c *kokoro.Context) error { return c.SendText("ok") })
+
+			for _, method := range []string{kokoro.MethodGet, kokoro.MethodOptions} {
+				resp := serve(s, method, "/users/1", tt.origin)
+				if got := string(resp.Header.Peek(kokoro.HeaderAccessControlAllowOrigin)); got != tt.want {
+					t.Errorf("%s: Access-Control-Allow-Origin = %q, want %q", method, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	s := kokoro.New()
+	s.Use(New(Config{AllowOrigins: []string{"https://app.example.com"}, AllowCredentials: true, MaxAge: 600}))
+	s.GET("/users/{id:int}", func(c *kokoro.Context) error { return c.SendText("ok") })
+	// Params conflicting across methods must not disable preflight handling.
+	s.POST("/users/{name:[a-z]+}", func(c *kokoro.Context) error { return c.SendText("ok") })
+
+	resp := serve(s, kokoro.MethodOptions, "/users/abc", "https://app.example.com")
+	if resp.StatusCode() != kokoro.StatusNoContent {
+		t.Fatalf("status = %d, want 204", resp.StatusCode())
+	}
+	for header, want := range map[string]string{
+		kokoro.HeaderAccessControlAllowCredentials: "true",
+		kokoro.HeaderAccessControlMaxAge:           "600",
+	} {
+		if got := string(resp.Header.Peek(header)); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestWildcardWithCredentialsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	New(Config{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestPanicKeepsHeaders(t *testing.T) {
+	s := kokoro.New()
+	s.OnPanic(func(*kokoro.Context, *kokoro.PanicError) {})
+	s.Use(New(Config{AllowOrigins: []string{"https://app.example.com"}, AllowCredentials: true, ExposeHeaders: []string{"X-Total"}}))
+	s.GET("/users/{id}", func(c *kokoro.Context) error {
+		c.SetHeader("X-Total", "3")
+		panic("boom")
+	})
+
+	resp := serve(s, kokoro.MethodGet, "/users/1", "https://app.example.com")
+	if resp.StatusCode() != kokoro.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode())
+	}
+	for header, want := range map[string]string{
+		kokoro.HeaderAccessControlAllowOrigin:      "https://app.example.com",
+		kokoro.HeaderAccessControlAllowCredentials: "true",
+		kokoro.HeaderAccessControlExposeHeaders:    "X-Total",
+		kokoro.HeaderVary:                          kokoro.HeaderOrigin,
+		"X-Total":                                  "",
+	} {
+		if got := string(resp.Header.Peek(header)); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Package csrf provides a Cross-Site Request Forgery protection middleware for Kokoro.
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/valyala/fasthttp"
+)
+
+// Errors wrapped by the 403 *kokoro.HTTPError returned for rejected requests,
+// for use with errors.Is.
+var (
+	ErrTokenMissing   = errors.New("csrf: token missing")
+	ErrTokenInvalid   = errors.New("csrf: token invalid")
+	ErrOriginMismatch = errors.New("csrf: origin mismatch")
+	ErrCrossSite      = errors.New("csrf: cross-site request")
+)
+
+// Mode selects where the expected token is kept between requests.
+type Mode int
+
+const (
+	// DoubleSubmitCookie keeps the token in a cookie; a request is accepted when
+	// it submits the same token through one of the extractors. Tokens are signed
+	// with Config.Key, so a cookie planted by a sibling subdomain is not accepted.
+	DoubleSubmitCookie Mode = iota
+
+	// SynchronizerToken keeps the token in the session configured with Config.Session.
+	SynchronizerToken
+)
+
+// Session is the storage used by the SynchronizerToken mode. Session subsystems
+// implement it to keep the token alongside the user's session data.
+type Session interface {
+	// Get returns the value stored under key in the request's session, or "".
+	Get(c *kokoro.Context, key string) string
+	// Set stores the value under key in the request's session.
+	Set(c *kokoro.Context, key, value string) error
+}
+
+// Extractor retrieves the token submitted with a request, or "" if absent.
+type Extractor func(c *kokoro.Context) string
+
+// FromHeader extracts the token from the named request header.
+func FromHeader(name string) Extractor {
+	return func(c *kokoro.Context) string { return c.Header(name) }
+}
+
+// FromForm extracts the token from the named URL-encoded or multipart form field.
+func FromForm(name string) Extractor {
+	return func(c *kokoro.Context) string { return c.FormValue(name) }
+}
+
+// FromQuery extracts the token from the named query parameter.
+func FromQuery(name string) Extractor {
+	return func(c *kokoro.Context) string { return c.Query(name) }
+}
+
+// Config defines the configuration for the CSRF middleware.
+type Config struct {
+	// Mode selects double-submit cookie or synchronizer token protection.
+	// Defaults to DoubleSubmitCookie.
+	Mode Mode
+
+	// Session stores the token in SynchronizerToken mode. Required for that mode.
+	Session Session
+
+	// SessionKey is the key of the token in the Session. Defaults to "csrf_token".
+	SessionKey string
+
+	// Extractors are tried in order to find the submitted token.
+	// Defaults to the "X-CSRF-Token" header, then the "_csrf" form field.
+	Extractors []Extractor
+
+	// Key signs the tokens of the DoubleSubmitCookie mode with HMAC-SHA256. Use at
+	// least 32 random bytes, shared by every instance of the application.
+	// Defaults to a random key, which invalidates issued tokens on restart.
+	Key []byte
+
+	// SessionID returns an identifier of the request's session, such as the value
+	// of the session cookie, that signed tokens are bound to, so they cannot be
+	// reused across sessions. Optional; only used in DoubleSubmitCookie mode.
+	SessionID func(c *kokoro.Context) string
+
+	// CookieName is the name of the token cookie in DoubleSubmitCookie mode.
+	// Defaults to "csrf_token".
+	CookieName string
+
+	// CookiePath, CookieDomain, CookieSecure and CookieMaxAge configure the token cookie.
+	// CookiePath defaults to "/" and CookieMaxAge to 12 hours.
+	CookiePath   string
+	CookieDomain string
+	CookieSecure bool
+	CookieMaxAge time.Duration
+
+	// TrustedOrigins lists origins, besides the request's own, allowed to submit
+	// unsafe requests, e.g. "https://admin.example.com".
+	TrustedOrigins []string
+
+	// AllowSameSite accepts requests whose Sec-Fetch-Site header is "same-site"
+	// in addition to "same-origin" and "none".
+	AllowSameSite bool
+
+	// Skip reports whether the request bypasses CSRF protection, e.g. for webhooks.
+	Skip func(c *kokoro.Context) bool
+}
+
+// New creates a CSRF protection middleware.
+//
+// Every request is issued a token, exposed through Context.CSRFToken for forms
+// and templates. Requests with unsafe methods are rejected with 403 when the
+// Sec-Fetch-Site header reports a cross-site request, when the Origin (or Referer)
+// is neither the request's own origin nor trusted, or when the submitted token
+// does not match the expected one.
+//
+// Example:
+//
+//	s.Use(csrf.New(csrf.Config{CookieSecure: true}))
+//
+//	s.GET("/token", func(c *kokoro.Context) error {
+//	    return c.SendJSON(kokoro.H{"csrf": c.CSRFToken()})
+//	})
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Mode == SynchronizerToken && cfg.Session == nil {
+		panic("csrf: SynchronizerToken mode requires Config.Session")
+	}
+	if len(cfg.Key) == 0 {
+		cfg.Key = make([]byte, 32)
+		_, _ = rand.Read(cfg.Key)
+	}
+	if cfg.SessionKey == "" {
+		cfg.SessionKey = "csrf_token"
+	}
+	if len(cfg.Extractors) == 0 {
+		cfg.Extractors = []Extractor{FromHeader("X-CSRF-Token"), FromForm("_csrf")}
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "csrf_token"
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.CookieMaxAge == 0 {
+		cfg.CookieMaxAge = 12 * time.Hour
+	}
+	trusted := make(map[string]struct{}, len(cfg.TrustedOrigins))
+	for _, o := range cfg.TrustedOrigins {
+		trusted[strings.ToLower(strings.TrimRight(o, "/"))] = struct{}{}
+	}
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			return next(c)
+		}
+
+		expected := cfg.load(c)
+
+		if !safeMethod(c.Method()) {
+			if err := checkFetchSite(c, cfg.AllowSameSite); err != nil {
+				return err
+			}
+			if err := checkOrigin(c, trusted); err != nil {
+				return err
+			}
+			if err := checkToken(c, cfg.Extractors, expected); err != nil {
+				return err
+			}
+		}
+
+		if expected == "" {
+			expected = cfg.newToken(c)
+			if err := cfg.store(c, expected); err != nil {
+				return err
+			}
+		}
+		c.SetCSRFToken(expected)
+		return next(c)
+	}
+}
+
+// load returns the token currently expected for the request. Cookies whose
+// signature does not verify are ignored, as if absent.
+func (cfg *Config) load(c *kokoro.Context) string {
+	if cfg.Mode == SynchronizerToken {
+		return cfg.Session.Get(c, cfg.SessionKey)
+	}
+	token := string(c.RequestCtx().Request.Header.Cookie(cfg.CookieName))
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(cfg.sign(c, nonce))) {
+		return ""
+	}
+	return token
+}
+
+// store persists a newly issued token.
+func (cfg *Config) store(c *kokoro.Context, token string) error {
+	if cfg.Mode == SynchronizerToken {
+		return cfg.Session.Set(c, cfg.SessionKey, token)
+	}
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(cfg.CookieName)
+	cookie.SetValue(token)
+	cookie.SetPath(cfg.CookiePath)
+	cookie.SetDomain(cfg.CookieDomain)
+	cookie.SetMaxAge(int(cfg.CookieMaxAge.Seconds()))
+	cookie.SetSecure(cfg.CookieSecure)
+	cookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
+	// The cookie stays readable by scripts so they can echo it in a header.
+	c.RequestCtx().Response.Header.SetCookie(cookie)
+	return nil
+}
+
+// safeMethod reports whether the method is safe as defined by RFC 9110, Section 9.2.1.
+func safeMethod(method string) bool {
+	switch method {
+	case kokoro.MethodGet, kokoro.MethodHead, kokoro.MethodOptions, kokoro.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// forbidden wraps cause into the 403 error returned for rejected requests.
+func forbidden(message string, cause error) error {
+	return kokoro.NewHTTPError(kokoro.StatusForbidden, message).WithCause(cause)
+}
+
+// checkFetchSite rejects requests a browser reports as cross-site.
+func checkFetchSite(c *kokoro.Context, allowSameSite bool) error {
+	switch c.Header("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+		return nil
+	case "same-site":
+		if allowSameSite {
+			return nil
+		}
+	}
+	return forbidden("cross-site request rejected", ErrCrossSite)
+}
+
+// checkOrigin rejects requests whose Origin, or Referer when Origin is absent,
+// is neither the request's own origin nor trusted. Requests carrying neither
+// header are only rejected over HTTPS, where browsers always send one.
+func checkOrigin(c *kokoro.Context, trusted map[string]struct{}) error {
+	origin := c.Header(kokoro.HeaderOrigin)
+	if origin == "" || origin == "null" {
+		if referer := c.Header(kokoro.HeaderReferer); referer != "" {
+			if u, err := url.Parse(referer); err == nil {
+				origin = u.Scheme + "://" + u.Host
+			}
+		}
+	}
+	if origin == "" {
+		if c.IsSecure() {
+			return forbidden("missing origin", ErrOriginMismatch)
+		}
+		return nil
+	}
+
+	origin = strings.ToLower(origin)
+	if origin == strings.ToLower(c.BaseURL()) {
+		return nil
+	}
+	if _, ok := trusted[origin]; ok {
+		return nil
+	}
+	return forbidden("origin not allowed", ErrOriginMismatch)
+}
+
+// checkToken compares the submitted token with the expected one in constant time.
+func checkToken(c *kokoro.Context, extractors []Extractor, expected string) error {
+	var submitted string
+	for _, extract := range extractors {
+		if submitted = extract(c); submitted != "" {
+			break
+		}
+	}
+	if submitted == "" || expected == "" {
+		return forbidden("missing CSRF token", ErrTokenMissing)
+	}
+	if subtle.ConstantTimeCompare([]byte(submitted), []byte(expected)) != 1 {
+		return forbidden("invalid CSRF token", ErrTokenInvalid)
+	}
+	return nil
+}
+
+// newToken returns a random URL-safe token of 32 bytes of entropy, followed in
+// DoubleSubmitCookie mode by its signature.
+func (cfg *Config) newToken(c *kokoro.Context) string {
+	var b [32]byte
+	_, _ = rand.Read(b[:])
+	nonce := base64.RawURLEncoding.EncodeToString(b[:])
+	if cfg.Mode == SynchronizerToken {
+		return nonce
+	}
+	return nonce + "." + cfg.sign(c, nonce)
+}
+
+// sign returns the signature of nonce for the request's session.
+func (cfg *Config) sign(c *kokoro.Context, nonce string) string {
+	mac := hmac.New(sha256.New, cfg.Key)
+	if cfg.SessionID != nil {
+		session := cfg.SessionID(c)
+		// The length prefix keeps the session and the nonce unambiguous.
+		mac.Write([]byte(strconv.Itoa(len(session)) + ":" + session))
+	}
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
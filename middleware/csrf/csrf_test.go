@@ -0,0 +1,149 @@
+package csrf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/valyala/fasthttp"
+)
+
+type request struct {
+	method  string
+	cookie  string
+	token   string
+	session string
+	origin  string
+	site    string
+}
+
+type memorySession map[string]string
+
+func (m memorySession) Get(c *kokoro.Context, key string) string { return m[c.Header("Session")+key] }
+
+func (m memorySession) Set(c *kokoro.Context, key, value string) error {
+	m[c.Header("Session")+key] = value
+	return nil
+}
+
+func newServer(cfg Config) (*kokoro.Server, *error) {
+	var handled error
+	s := kokoro.New()
+	s.SetErrorHandler(func(c *kokoro.Context, err error) error {
+		handled = err
+		return c.Status(kokoro.StatusForbidden).SendText(err.Error())
+	})
+	s.Use(New(cfg))
+	ok := func(c *kokoro.Context) error { return c.SendText(c.CSRFToken()) }
+	s.GET("/form", ok)
+	s.POST("/form", ok)
+	return s, &handled
+}
+
+func serve(s *kokoro.Server, r request) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(r.method)
+	ctx.Request.Header.SetHost("example.com")
+	ctx.Request.SetRequestURI("/form")
+	for header, value := range map[string]string{
+		"X-CSRF-Token":      r.token,
+		"Session":           r.session,
+		kokoro.HeaderOrigin: r.origin,
+		"Sec-Fetch-Site":    r.site,
+	} {
+		if value != "" {
+			ctx.Request.Header.Set(header, value)
+		}
+	}
+	if r.cookie != "" {
+		ctx.Request.Header.SetCookie("csrf_token", r.cookie)
+	}
+	s.Handler(ctx)
+	return ctx
+}
+
+// issue returns the token issued to a first visit in the given session.
+func issue(t *testing.T, s *kokoro.Server, session string) string {
+	t.Helper()
+	ctx := serve(s, request{method: kokoro.MethodGet, session: session})
+	if ctx.Response.StatusCode() != kokoro.StatusOK {
+		t.Fatalf("GET status = %d", ctx.Response.StatusCode())
+	}
+	return string(ctx.Response.Body())
+}
+
+func TestDoubleSubmitCookie(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	cfg := Config{
+		Key:            key,
+		SessionID:      func(c *kokoro.Context) string { return c.Header("Session") },
+		TrustedOrigins: []string{"https://admin.example.com"},
+	}
+	s, handled := newServer(cfg)
+	token := issue(t, s, "alice")
+	other, _ := newServer(Config{Key: []byte("another key, unknown to the app.")})
+	foreign := issue(t, other, "alice")
+
+	tests := []struct {
+		name string
+		req  request
+		want error
+	}{
+		{"valid", request{cookie: token, token: token, session: "alice"}, nil},
+		{"same origin", request{cookie: token, token: token, session: "alice", origin: "http://example.com"}, nil},
+		{"trusted origin", request{cookie: token, token: token, session: "alice", origin: "https://admin.example.com"}, nil},
+		{"same-origin fetch", request{cookie: token, token: token, session: "alice", site: "same-origin"}, nil},
+		{"missing token", request{cookie: token, session: "alice"}, ErrTokenMissing},
+		{"missing cookie", request{token: token, session: "alice"}, ErrTokenMissing},
+		{"mismatched token", request{cookie: token, token: token[:len(token)-1] + "A", session: "alice"}, ErrTokenInvalid},
+		{"unsigned cookie", request{cookie: "planted", token: "planted", session: "alice"}, ErrTokenMissing},
+		{"forged signature", request{cookie: "planted.c2ln", token: "planted.c2ln", session: "alice"}, ErrTokenMissing},
+		{"other key", request{cookie: foreign, token: foreign, session: "alice"}, ErrTokenMissing},
+		{"other session", request{cookie: token, token: token, session: "mallory"}, ErrTokenMissing},
+		{"foreign origin", request{cookie: token, token: token, session: "alice", origin: "https://evil.io"}, ErrOriginMismatch},
+		{"cross-site fetch", request{cookie: token, token: token, session: "alice", site: "cross-site"}, ErrCrossSite},
+		{"same-site fetch", request{cookie: token, token: token, session: "alice", site: "same-site"}, ErrCrossSite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*handled = nil
+			tt.req.method = kokoro.MethodPost
+			ctx := serve(s, tt.req)
+
+			if !errors.Is(*handled, tt.want) || (tt.want == nil) != (*handled == nil) {
+				t.Fatalf("error = %v, want %v", *handled, tt.want)
+			}
+			if tt.want == nil && string(ctx.Response.Body()) != token {
+				t.Errorf("CSRFToken = %q, want %q", ctx.Response.Body(), token)
+			}
+		})
+	}
+}
+
+func TestSynchronizerToken(t *testing.T) {
+	s, handled := newServer(Config{Mode: SynchronizerToken, Session: memorySession{}})
+	token := issue(t, s, "alice")
+	issue(t, s, "mallory")
+
+	tests := []struct {
+		name string
+		req  request
+		want error
+	}{
+		{"valid", request{token: token, session: "alice"}, nil},
+		{"missing token", request{session: "alice"}, ErrTokenMissing},
+		{"no session", request{token: token, session: "bob"}, ErrTokenMissing},
+		{"other session", request{token: token, session: "mallory"}, ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*handled = nil
+			tt.req.method = kokoro.MethodPost
+			serve(s, tt.req)
+
+			if !errors.Is(*handled, tt.want) || (tt.want == nil) != (*handled == nil) {
+				t.Fatalf("error = %v, want %v", *handled, tt.want)
+			}
+		})
+	}
+}
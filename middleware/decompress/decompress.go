@@ -0,0 +1,201 @@
+// Package decompress provides a middleware for Kokoro that transparently decompresses
+// request bodies and limits their size.
+package decompress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Errors wrapped by the *kokoro.HTTPError returned for rejected requests, for use
+// with errors.Is.
+var (
+	ErrTooLarge            = errors.New("decompress: request body too large")
+	ErrUnsupportedEncoding = errors.New("decompress: unsupported content encoding")
+	ErrMalformed           = errors.New("decompress: malformed request body")
+)
+
+// Config defines the configuration for the decompression middleware.
+type Config struct {
+	// MaxSize is the maximum size, in bytes, of the request body once decompressed.
+	// It also applies to uncompressed bodies. Defaults to 10 MiB.
+	MaxSize int64
+
+	// Encodings lists the accepted content encodings, among gzip, deflate, br and
+	// zstd. Defaults to all of them.
+	Encodings []string
+}
+
+// New creates a request decompression middleware.
+//
+// Bodies encoded with one or more of the accepted encodings are decoded in place,
+// and the Content-Encoding header is removed, so handlers read them as if they
+// had been sent uncompressed. Decoding stops as soon as MaxSize is exceeded,
+// which protects against decompression bombs.
+//
+// With the Server's StreamRequestBody option, encoded bodies and bodies of unknown
+// length are decoded or read from the stream, up to MaxSize, and buffered in
+// memory for the handler. Uncompressed bodies of known length stay streamed.
+//
+// Requests are rejected with 413 when the body exceeds MaxSize, 415 when it uses
+// an encoding that is not accepted, and 400 when it cannot be decoded. These errors
+// are rendered by the error handlers.
+//
+// Since the limit is part of the configuration, different routes can be given
+// different limits by passing the middleware to the route:
+//
+//	s.POST("/telemetry", ingest, decompress.New(decompress.Config{MaxSize: 1 << 20}))
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 << 20
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{"gzip", "deflate", "br", "zstd"}
+	}
+	accepted := make(map[string]bool, len(cfg.Encodings))
+	for _, e := range cfg.Encodings {
+		e = strings.ToLower(e)
+		if _, ok := decoders[e]; !ok {
+			panic("decompress: unsupported encoding " + e)
+		}
+		accepted[e] = true
+	}
+	acceptEncoding := strings.Join(cfg.Encodings, ", ")
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		req := &c.RequestCtx().Request
+		length := req.Header.ContentLength()
+		if length > 0 && int64(length) > cfg.MaxSize {
+			return tooLarge(cfg.MaxSize)
+		}
+
+		var encodings []string
+		for _, e := range strings.Split(string(req.Header.ContentEncoding()), ",") {
+			if e = strings.ToLower(strings.TrimSpace(e)); e != "" && e != "identity" {
+				encodings = append(encodings, e)
+			}
+		}
+		if len(encodings) == 0 {
+			switch {
+			case !req.IsBodyStream():
+				if int64(len(req.Body())) > cfg.MaxSize {
+					return tooLarge(cfg.MaxSize)
+				}
+			case length < 0:
+				// A streamed body of unknown length is read, up to the limit, so it can be enforced.
+				body, err := io.ReadAll(kokoro.LimitReader(req.BodyStream(), cfg.MaxSize, ErrTooLarge))
+				if err != nil {
+					return streamError(c, cfg.MaxSize, err)
+				}
+				req.SetBodyRaw(body)
+				req.Header.SetContentLength(len(body))
+			}
+			return next(c)
+		}
+
+		for _, e := range encodings {
+			if !accepted[e] {
+				// RFC 9110, Section 15.5.16: advertise the accepted encodings.
+				c.SetHeader(kokoro.HeaderAcceptEncoding, acceptEncoding)
+				return kokoro.NewHTTPError(kokoro.StatusUnsupportedMediaType,
+					"Unsupported Content-Encoding "+strconv.Quote(e)).WithCause(ErrUnsupportedEncoding)
+			}
+		}
+
+		// Streamed bodies are decoded as they are read, never buffering more than
+		// MaxSize bytes of either the encoded or the decoded body.
+		var r io.Reader
+		if req.IsBodyStream() {
+			r = kokoro.LimitReader(req.BodyStream(), cfg.MaxSize, ErrTooLarge)
+		} else {
+			r = bytes.NewReader(req.Body())
+		}
+		var body []byte
+		// Encodings are listed in the order they were applied, so they are undone in reverse.
+		for i := len(encodings) - 1; i >= 0; i-- {
+			var err error
+			if body, err = decode(encodings[i], r, cfg.MaxSize); err != nil {
+				if req.IsBodyStream() {
+					return streamError(c, cfg.MaxSize, err)
+				}
+				return decodeError(cfg.MaxSize, err)
+			}
+			r = bytes.NewReader(body)
+		}
+
+		req.SetBodyRaw(body)
+		req.Header.SetContentLength(len(body))
+		req.Header.Del(kokoro.HeaderContentEncoding)
+		return next(c)
+	}
+}
+
+// decoders opens a decompressing reader for each supported encoding.
+var decoders = map[string]func(r io.Reader, limit int64) (io.ReadCloser, error){
+	"gzip": func(r io.Reader, _ int64) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	// HTTP's deflate encoding is the zlib format, as defined by RFC 9110, Section 8.4.1.2.
+	"deflate": func(r io.Reader, _ int64) (io.ReadCloser, error) {
+		return zlib.NewReader(r)
+	},
+	"br": func(r io.Reader, _ int64) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader, limit int64) (io.ReadCloser, error) {
+		// Bound the window a frame may request, so a crafted header cannot force a large allocation.
+		window := uint64(min(max(limit, 8<<20), zstd.MaxWindowSize))
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(window))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// decode decompresses what is read from body with the named encoding, failing with
+// ErrTooLarge once more than limit bytes have been produced.
+func decode(encoding string, body io.Reader, limit int64) ([]byte, error) {
+	r, err := decoders[encoding](body, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(kokoro.LimitReader(r, limit, ErrTooLarge))
+}
+
+// tooLarge returns the 413 error for bodies over limit bytes.
+func tooLarge(limit int64) error {
+	return kokoro.NewHTTPError(kokoro.StatusPayloadTooLarge,
+		"Request body exceeds "+strconv.FormatInt(limit, 10)+" bytes").WithCause(ErrTooLarge)
+}
+
+// decodeError returns the error answering a body that failed to decode.
+func decodeError(limit int64, err error) error {
+	if errors.Is(err, ErrTooLarge) {
+		return tooLarge(limit)
+	}
+	return kokoro.NewHTTPError(kokoro.StatusBadRequest, "Malformed request body").
+		WithCause(errors.Join(ErrMalformed, err))
+}
+
+// streamError is like decodeError for streamed bodies, which may not have been
+// read entirely; the connection is then closed rather than reused.
+func streamError(c *kokoro.Context, limit int64, err error) error {
+	c.RequestCtx().SetConnectionClose()
+	return decodeError(limit, err)
+}
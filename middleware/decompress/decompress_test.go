@@ -0,0 +1,211 @@
+package decompress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+var text = strings.Repeat("kokoro decompresses request bodies. ", 20)
+
+// encode compresses data with the encodings, in the order they are listed.
+func encode(t *testing.T, data string, encodings ...string) []byte {
+	t.Helper()
+	body := []byte(data)
+	for _, e := range encodings {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch e {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		case "zstd":
+			var err error
+			if w, err = zstd.NewWriter(&buf); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatalf("unknown encoding %q", e)
+		}
+		w.Write(body)
+		w.Close()
+		body = buf.Bytes()
+	}
+	return body
+}
+
+// echo answers with the Content-Length and the Content-Encoding of the request,
+// followed by its body.
+func echo(c *kokoro.Context) error {
+	h := &c.RequestCtx().Request.Header
+	return c.SendText(fmt.Sprintf("%d|%s|%s", h.ContentLength(), h.ContentEncoding(), c.PostBody()))
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		encoding string
+		body     []byte
+		length   int // Content-Length sent instead of the body's length, if not 0.
+		status   int
+		cause    error
+		left     string // Content-Encoding left for the handler.
+	}{
+		{"plain", Config{}, "", []byte(text), 0, kokoro.StatusOK, nil, ""},
+		{"identity", Config{}, "identity", []byte(text), 0, kokoro.StatusOK, nil, "identity"},
+		{"gzip", Config{}, "gzip", encode(t, text, "gzip"), 0, kokoro.StatusOK, nil, ""},
+		{"deflate", Config{}, "deflate", encode(t, text, "deflate"), 0, kokoro.StatusOK, nil, ""},
+		{"brotli", Config{}, "br", encode(t, text, "br"), 0, kokoro.StatusOK, nil, ""},
+		{"zstd", Config{}, "zstd", encode(t, text, "zstd"), 0, kokoro.StatusOK, nil, ""},
+		{"stacked", Config{}, "gzip, br", encode(t, text, "gzip", "br"), 0, kokoro.StatusOK, nil, ""},
+		{"case-insensitive", Config{}, "GZip", encode(t, text, "gzip"), 0, kokoro.StatusOK, nil, ""},
+		{"at limit", Config{MaxSize: int64(len(text))}, "gzip", encode(t, text, "gzip"), 0, kokoro.StatusOK, nil, ""},
+		{"decoded too large", Config{MaxSize: int64(len(text)) - 1}, "gzip", encode(t, text, "gzip"), 0, kokoro.StatusPayloadTooLarge, ErrTooLarge, ""},
+		{"bomb", Config{MaxSize: 1 << 10}, "zstd", encode(t, strings.Repeat("0", 10<<20), "zstd"), 0, kokoro.StatusPayloadTooLarge, ErrTooLarge, ""},
+		{"plain too large", Config{MaxSize: 10}, "", []byte(text), 0, kokoro.StatusPayloadTooLarge, ErrTooLarge, ""},
+		{"declared too large", Config{MaxSize: 10}, "", []byte("tiny"), 1 << 20, kokoro.StatusPayloadTooLarge, ErrTooLarge, ""},
+		{"unsupported", Config{}, "compress", []byte(text), 0, kokoro.StatusUnsupportedMediaType, ErrUnsupportedEncoding, ""},
+		{"not accepted", Config{Encodings: []string{"gzip"}}, "br", encode(t, text, "br"), 0, kokoro.StatusUnsupportedMediaType, ErrUnsupportedEncoding, ""},
+		{"malformed", Config{}, "gzip", []byte("not gzip"), 0, kokoro.StatusBadRequest, ErrMalformed, ""},
+		{"truncated", Config{}, "br", encode(t, text, "br")[:20], 0, kokoro.StatusBadRequest, ErrMalformed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled error
+			s := kokoro.New()
+			s.SetErrorHandler(func(c *kokoro.Context, err error) error {
+				handled = err
+				var he *kokoro.HTTPError
+				if !errors.As(err, &he) {
+					return err
+				}
+				return c.Status(he.Code).SendText(he.Message)
+			})
+			s.Use(New(tt.cfg))
+			s.POST("/", echo)
+
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+			ctx.Request.SetRequestURI("/")
+			ctx.Request.SetBody(tt.body)
+			if tt.encoding != "" {
+				ctx.Request.Header.Set(kokoro.HeaderContentEncoding, tt.encoding)
+			}
+			if tt.length != 0 {
+				ctx.Request.Header.SetContentLength(tt.length)
+			} else {
+				ctx.Request.Header.SetContentLength(len(tt.body))
+			}
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d (error %v)", got, tt.status, handled)
+			}
+			if tt.cause != nil && !errors.Is(handled, tt.cause) {
+				t.Errorf("error = %v, want %v", handled, tt.cause)
+			}
+			if tt.status == kokoro.StatusUnsupportedMediaType {
+				want := strings.Join(tt.cfg.Encodings, ", ")
+				if want == "" {
+					want = "gzip, deflate, br, zstd"
+				}
+				if got := string(ctx.Response.Header.Peek(kokoro.HeaderAcceptEncoding)); got != want {
+					t.Errorf("Accept-Encoding = %q, want %q", got, want)
+				}
+			}
+			if tt.status != kokoro.StatusOK {
+				return
+			}
+			if got, want := string(ctx.Response.Body()), fmt.Sprintf("%d|%s|%s", len(text), tt.left, text); got != want {
+				t.Errorf("handler saw %.30q..., want %.30q...", got, want)
+			}
+		})
+	}
+}
+
+func TestDecompressStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		chunked  bool
+		maxSize  int64
+		status   int
+		want     string
+	}{
+		{"gzip", "gzip", encode(t, text, "gzip"), false, 0, kokoro.StatusOK, fmt.Sprintf("%d||%s", len(text), text)},
+		{"gzip chunked", "gzip", encode(t, text, "gzip"), true, 0, kokoro.StatusOK, fmt.Sprintf("%d||%s", len(text), text)},
+		{"plain chunked", "", []byte(text), true, 0, kokoro.StatusOK, fmt.Sprintf("%d||%s", len(text), text)},
+		{"plain chunked too large", "", []byte(text), true, 10, kokoro.StatusPayloadTooLarge, ""},
+		{"gzip too large", "gzip", encode(t, text, "gzip"), true, 10, kokoro.StatusPayloadTooLarge, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := kokoro.New()
+			s.Use(New(Config{MaxSize: tt.maxSize}))
+			s.POST("/", func(c *kokoro.Context) error {
+				if te := c.RequestCtx().Request.Header.Peek(kokoro.HeaderTransferEncoding); len(te) > 0 {
+					return fmt.Errorf("Transfer-Encoding %q left on a buffered body", te)
+				}
+				return echo(c)
+			})
+
+			ln := fasthttputil.NewInmemoryListener()
+			defer ln.Close()
+			srv := &fasthttp.Server{Handler: s.Handler, StreamRequestBody: true, MaxRequestBodySize: 16}
+			go srv.Serve(ln)
+			client := &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+
+			req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(req)
+			defer fasthttp.ReleaseResponse(resp)
+			req.SetRequestURI("http://example.com/")
+			req.Header.SetMethod(fasthttp.MethodPost)
+			if tt.encoding != "" {
+				req.Header.Set(kokoro.HeaderContentEncoding, tt.encoding)
+			}
+			if tt.chunked {
+				req.SetBodyStream(bytes.NewReader(tt.body), -1)
+			} else {
+				req.SetBody(tt.body)
+			}
+			if err := client.Do(req, resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resp.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d: %s", got, tt.status, resp.Body())
+			}
+			if tt.want != "" {
+				if got := string(resp.Body()); got != tt.want {
+					t.Errorf("handler saw %.30q..., want %.30q...", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPanicsOnUnknownEncoding(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New with an unknown encoding did not panic")
+		}
+	}()
+	New(Config{Encodings: []string{"lzma"}})
+}
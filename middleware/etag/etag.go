@@ -0,0 +1,59 @@
+// Package etag provides a middleware for Kokoro that sets ETag headers and answers
+// conditional GET requests with 304 Not Modified.
+package etag
+
+import (
+	"github.com/Abhishek2010dev/kokoro"
+)
+
+// Config defines the configuration for the ETag middleware.
+type Config struct {
+	// Weak generates weak entity tags, only claiming semantic equivalence. Use it
+	// when equivalent responses may differ byte-wise, e.g. through compression.
+	Weak bool
+
+	// Skip reports whether the request bypasses the middleware.
+	Skip func(c *kokoro.Context) bool
+}
+
+// New creates an ETag middleware.
+//
+// Successful responses to GET and HEAD requests without an ETag are given one
+// computed from their body with kokoro.GenerateETag. Streamed bodies are not
+// hashed, but an ETag set by the handler is honored. When Context.Fresh reports
+// that the client's cached copy is still valid, through If-None-Match or
+// If-Modified-Since, the response is replaced by Context.NotModified.
+//
+// Example:
+//
+//	s.Use(etag.New())
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			return next(c)
+		}
+		if err := next(c); err != nil {
+			return err
+		}
+
+		method := c.Method()
+		if (method != kokoro.MethodGet && method != kokoro.MethodHead) || c.StatusCode() != kokoro.StatusOK {
+			return nil
+		}
+
+		resp := &c.RequestCtx().Response
+		if len(resp.Header.Peek(kokoro.HeaderETag)) == 0 && !resp.IsBodyStream() {
+			resp.Header.Set(kokoro.HeaderETag, kokoro.GenerateETag(resp.Body(), cfg.Weak))
+		}
+
+		if c.Fresh() {
+			return c.NotModified()
+		}
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package etag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/valyala/fasthttp"
+)
+
+func TestETag(t *testing.T) {
+	tag := kokoro.GenerateETag([]byte("hello"), false)
+	weakTag := kokoro.GenerateETag([]byte("hello"), true)
+	lastModified := "Wed, 01 May 2024 10:00:00 GMT"
+
+	s := kokoro.New()
+	s.Use(New(Config{Skip: func(c *kokoro.Context) bool { return strings.HasPrefix(c.Path(), "/skip") }}))
+	s.GET("/text", func(c *kokoro.Context) error { return c.SendText("hello") })
+	s.GET("/preset", func(c *kokoro.Context) error {
+		c.SetHeader(kokoro.HeaderETag, `"v1"`)
+		return c.SendText("hello")
+	})
+	s.GET("/stream", func(c *kokoro.Context) error { return c.SendStream(strings.NewReader("hello"), -1) })
+	s.GET("/modified", func(c *kokoro.Context) error {
+		c.SetHeader(kokoro.HeaderLastModified, lastModified)
+		c.SetHeader(kokoro.HeaderETag, `"v1"`)
+		return c.SendText("hello")
+	})
+	s.GET("/created", func(c *kokoro.Context) error { return c.Status(kokoro.StatusCreated).SendText("hello") })
+	s.POST("/text", func(c *kokoro.Context) error { return c.SendText("hello") })
+	s.GET("/skip", func(c *kokoro.Context) error { return c.SendText("hello") })
+	weak := kokoro.New()
+	weak.Use(New(Config{Weak: true}))
+	weak.GET("/text", func(c *kokoro.Context) error { return c.SendText("hello") })
+
+	tests := []struct {
+		name    string
+		server  *kokoro.Server
+		method  string
+		path    string
+		headers [][2]string
+		status  int
+		etag    string
+	}{
+		{"generated", s, kokoro.MethodGet, "/text", nil, kokoro.StatusOK, tag},
+		{"head", s, kokoro.MethodHead, "/text", nil, kokoro.StatusOK, tag},
+		{"weak", weak, kokoro.MethodGet, "/text", nil, kokoro.StatusOK, weakTag},
+		{"match", s, kokoro.MethodGet, "/text", [][2]string{{kokoro.HeaderIfNoneMatch, tag}}, kokoro.StatusNotModified, tag},
+		{"weak match", s, kokoro.MethodGet, "/text", [][2]string{{kokoro.HeaderIfNoneMatch, weakTag}}, kokoro.StatusNotModified, tag},
+		{"match in list", s, kokoro.MethodGet, "/text", [][2]string{{kokoro.HeaderIfNoneMatch, `"a", ` + tag}}, kokoro.StatusNotModified, tag},
+		{"any", s, kokoro.MethodGet, "/text", [][2]string{{kokoro.HeaderIfNoneMatch, "*"}}, kokoro.StatusNotModified, tag},
+		{"no match", s, kokoro.MethodGet, "/text", [][2]string{{kokoro.HeaderIfNoneMatch, `"other"`}}, kokoro.StatusOK, tag},
+		{"no-cache", s, kokoro.MethodGet, "/text", [][2]string{{kokoro.HeaderIfNoneMatch, tag}, {kokoro.HeaderCacheControl, "no-cache"}}, kokoro.StatusOK, tag},
+		{"preset kept", s, kokoro.MethodGet, "/preset", nil, kokoro.StatusOK, `"v1"`},
+		{"preset match", s, kokoro.MethodGet, "/preset", [][2]string{{kokoro.HeaderIfNoneMatch, `"v1"`}}, kokoro.StatusNotModified, `"v1"`},
+		{"stream not hashed", s, kokoro.MethodGet, "/stream", nil, kokoro.StatusOK, ""},
+		{"not modified since", s, kokoro.MethodGet, "/modified", [][2]string{{kokoro.HeaderIfModifiedSince, lastModified}}, kokoro.StatusNotModified, `"v1"`},
+		{"modified since", s, kokoro.MethodGet, "/modified", [][2]string{{kokoro.HeaderIfModifiedSince, "Tue, 30 Apr 2024 10:00:00 GMT"}}, kokoro.StatusOK, `"v1"`},
+		{"if-none-match wins", s, kokoro.MethodGet, "/modified", [][2]string{{kokoro.HeaderIfNoneMatch, `"v2"`}, {kokoro.HeaderIfModifiedSince, lastModified}}, kokoro.StatusOK, `"v1"`},
+		{"not 200", s, kokoro.MethodGet, "/created", [][2]string{{kokoro.HeaderIfNoneMatch, "*"}}, kokoro.StatusCreated, ""},
+		{"not GET", s, kokoro.MethodPost, "/text", [][2]string{{kokoro.HeaderIfNoneMatch, "*"}}, kokoro.StatusOK, ""},
+		{"skipped", s, kokoro.MethodGet, "/skip", nil, kokoro.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(tt.method)
+			ctx.Request.SetRequestURI(tt.path)
+			for _, h := range tt.headers {
+				ctx.Request.Header.Set(h[0], h[1])
+			}
+			tt.server.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if got := string(ctx.Response.Header.Peek(kokoro.HeaderETag)); got != tt.etag {
+				t.Errorf("ETag = %q, want %q", got, tt.etag)
+			}
+			if tt.status == kokoro.StatusNotModified {
+				if len(ctx.Response.Body()) != 0 || len(ctx.Response.Header.Peek(kokoro.HeaderContentType)) != 0 {
+					t.Errorf("304 has a body or Content-Type")
+				}
+			}
+		})
+	}
+}
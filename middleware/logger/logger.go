@@ -0,0 +1,274 @@
+// Package logger provides an access logging middleware for Kokoro built on log/slog.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Abhishek2010dev/kokoro"
+)
+
+// Field identifies a request attribute recorded by the logger.
+type Field string
+
+const (
+	FieldMethod    Field = "method"     // HTTP method.
+	FieldPath      Field = "path"       // Request path.
+	FieldRoute     Field = "route"      // Pattern of the matched route.
+	FieldStatus    Field = "status"     // Response status code.
+	FieldLatency   Field = "latency"    // Time spent handling the request.
+	FieldBytesIn   Field = "bytes_in"   // Size of the request body, from its Content-Length.
+	FieldBytesOut  Field = "bytes_out"  // Size of the response body.
+	FieldIP        Field = "ip"         // Client IP as reported by Context.RealIP.
+	FieldRequestID Field = "request_id" // Value of the X-Request-ID header.
+	FieldUserAgent Field = "user_agent" // Value of the User-Agent header.
+	FieldError     Field = "error"      // Error returned by the handler, if any.
+)
+
+// DefaultFields are the fields recorded when Config.Fields is empty.
+var DefaultFields = []Field{
+	FieldMethod, FieldPath, FieldRoute, FieldStatus, FieldLatency,
+	FieldBytesIn, FieldBytesOut, FieldIP, FieldRequestID, FieldError,
+}
+
+// Format selects how each request is recorded.
+type Format int
+
+const (
+	// FormatStructured records requests as slog records with one attribute per field.
+	FormatStructured Format = iota
+
+	// FormatCommon writes lines in the NCSA Common Log Format to Config.Output.
+	FormatCommon
+
+	// FormatCombined writes lines in the NCSA Combined Log Format, which adds the
+	// Referer and User-Agent headers to the Common Log Format, to Config.Output.
+	FormatCombined
+)
+
+// Config defines the configuration for the logger middleware.
+type Config struct {
+	// Handler receives the structured records. Defaults to the handler of slog.Default().
+	Handler slog.Handler
+
+	// Message is the message of structured records. Defaults to "request".
+	Message string
+
+	// Fields lists the attributes recorded in structured records. Defaults to DefaultFields.
+	Fields []Field
+
+	// Format selects structured records or a text log format. Defaults to FormatStructured.
+	Format Format
+
+	// Output receives the lines of FormatCommon and FormatCombined. Defaults to os.Stdout.
+	Output io.Writer
+
+	// SkipPaths lists request paths that are never logged, e.g. "/healthz".
+	SkipPaths []string
+
+	// Skip reports whether the request should not be logged.
+	Skip func(c *kokoro.Context) bool
+
+	// SampleRate is the fraction, in (0, 1], of requests answered with a status
+	// below 400 that are logged. Errors are always logged. Zero logs every request.
+	SampleRate float64
+}
+
+// New creates an access logging middleware.
+//
+// Records are emitted at level Info, Warn for 4xx responses and Error for 5xx responses.
+// Errors returned by the next handler are passed to the error handlers before the
+// record is emitted, so the logged status is the one sent to the client. Requests
+// whose handler panics are logged with status 500 before the panic is passed on to
+// the Server's recovery.
+//
+// Example:
+//
+//	s.Use(logger.New(logger.Config{
+//	    Handler:   slog.NewJSONHandler(os.Stderr, nil),
+//	    SkipPaths: []string{"/healthz"},
+//	}))
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Handler == nil {
+		cfg.Handler = slog.Default().Handler()
+	}
+	if cfg.Message == "" {
+		cfg.Message = "request"
+	}
+	if len(cfg.Fields) == 0 {
+		cfg.Fields = DefaultFields
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	skip := make(map[string]struct{}, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = struct{}{}
+	}
+	var mu sync.Mutex // Serializes the lines written to cfg.Output.
+
+	log := func(c *kokoro.Context, start time.Time, status int, err error) {
+		latency := time.Since(start)
+		if status < 400 && cfg.SampleRate > 0 && cfg.SampleRate < 1 && rand.Float64() >= cfg.SampleRate {
+			return
+		}
+
+		switch cfg.Format {
+		case FormatCommon, FormatCombined:
+			line := textLine(c, cfg.Format, start, status)
+			mu.Lock()
+			_, _ = io.WriteString(cfg.Output, line)
+			mu.Unlock()
+		default:
+			record(c, &cfg, start, latency, status, err)
+		}
+	}
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		if _, ok := skip[c.Path()]; ok || (cfg.Skip != nil && cfg.Skip(c)) {
+			return next(c)
+		}
+
+		start := time.Now()
+		defer func() {
+			if v := recover(); v != nil {
+				log(c, start, kokoro.StatusInternalServerError, fmt.Errorf("panic: %v", v))
+				panic(v)
+			}
+		}()
+
+		err := next(c)
+		if err != nil {
+			c.HandleError(err)
+		}
+		log(c, start, c.StatusCode(), err)
+		return nil
+	}
+}
+
+// record emits a structured record with the configured fields.
+func record(c *kokoro.Context, cfg *Config, start time.Time, latency time.Duration, status int, err error) {
+	level := slog.LevelInfo
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	}
+
+	ctx := context.Background()
+	if !cfg.Handler.Enabled(ctx, level) {
+		return
+	}
+
+	r := slog.NewRecord(start, level, cfg.Message, 0)
+	for _, f := range cfg.Fields {
+		key := string(f)
+		switch f {
+		case FieldMethod:
+			r.AddAttrs(slog.String(key, c.Method()))
+		case FieldPath:
+			r.AddAttrs(slog.String(key, c.Path()))
+		case FieldRoute:
+			r.AddAttrs(slog.String(key, c.Route()))
+		case FieldStatus:
+			r.AddAttrs(slog.Int(key, status))
+		case FieldLatency:
+			r.AddAttrs(slog.Duration(key, latency))
+		case FieldBytesIn:
+			r.AddAttrs(slog.Int(key, bytesIn(c)))
+		case FieldBytesOut:
+			r.AddAttrs(slog.Int(key, bytesOut(c)))
+		case FieldIP:
+			r.AddAttrs(slog.String(key, c.RealIP()))
+		case FieldRequestID:
+			if id := requestID(c); id != "" {
+				r.AddAttrs(slog.String(key, id))
+			}
+		case FieldUserAgent:
+			r.AddAttrs(slog.String(key, c.Header(kokoro.HeaderUserAgent)))
+		case FieldError:
+			if err != nil {
+				r.AddAttrs(slog.String(key, err.Error()))
+			}
+		}
+	}
+	_ = cfg.Handler.Handle(ctx, r)
+}
+
+// textLine formats the request in the Common or Combined Log Format.
+func textLine(c *kokoro.Context, format Format, start time.Time, status int) string {
+	var b strings.Builder
+	b.WriteString(c.RealIP())
+	b.WriteString(" - - [")
+	b.WriteString(start.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString("] \"")
+	b.WriteString(c.Method())
+	b.WriteByte(' ')
+	b.WriteString(c.URL())
+	b.WriteByte(' ')
+	b.WriteString(c.Protocol())
+	b.WriteString("\" ")
+	b.WriteString(strconv.Itoa(status))
+	b.WriteByte(' ')
+	if n := bytesOut(c); n > 0 {
+		b.WriteString(strconv.Itoa(n))
+	} else {
+		b.WriteByte('-')
+	}
+	if format == FormatCombined {
+		b.WriteString(" \"")
+		b.WriteString(headerOrDash(c, kokoro.HeaderReferer))
+		b.WriteString("\" \"")
+		b.WriteString(headerOrDash(c, kokoro.HeaderUserAgent))
+		b.WriteByte('"')
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// headerOrDash returns the request header value with quotes escaped, or "-" if absent.
+func headerOrDash(c *kokoro.Context, key string) string {
+	v := c.Header(key)
+	if v == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
+
+// bytesIn returns the declared size of the request body, without reading a
+// streamed body, or 0 when unknown.
+func bytesIn(c *kokoro.Context) int {
+	return max(c.RequestCtx().Request.Header.ContentLength(), 0)
+}
+
+// bytesOut returns the size of the response body, using the declared
+// Content-Length for streamed bodies.
+func bytesOut(c *kokoro.Context) int {
+	resp := &c.RequestCtx().Response
+	if resp.IsBodyStream() {
+		return max(resp.Header.ContentLength(), 0)
+	}
+	return len(resp.Body())
+}
+
+// requestID returns the request ID echoed in the response, falling back to the
+// one sent by the client.
+func requestID(c *kokoro.Context) string {
+	if id := c.RequestCtx().Response.Header.Peek(kokoro.HeaderXRequestID); len(id) > 0 {
+		return string(id)
+	}
+	return c.Header(kokoro.HeaderXRequestID)
+}
@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Result is the outcome of taking one request from a key's quota.
+type Result struct {
+	Allowed    bool          // Whether the request may proceed.
+	Limit      int           // Maximum number of requests in a full quota.
+	Remaining  int           // Requests left after this one.
+	Reset      time.Duration // Time until the quota is fully available again.
+	RetryAfter time.Duration // Time until a request will be allowed, when denied.
+}
+
+// Algorithm is a rate limiting algorithm. Its per-key state is opaque bytes, so
+// any Store can keep it.
+type Algorithm interface {
+	// Take consumes one request from the state at time now. It returns the result
+	// and the new state; a nil or malformed state is treated as a fresh key.
+	Take(state []byte, now time.Time) (Result, []byte)
+
+	// TTL is how long an untouched state must be kept before it can be discarded.
+	TTL() time.Duration
+}
+
+// TokenBucket returns an Algorithm allowing bursts of up to burst requests,
+// refilled at rate requests per period. It panics unless rate and per are positive.
+func TokenBucket(rate int, per time.Duration, burst int) Algorithm {
+	checkRate("TokenBucket", rate, per)
+	return &tokenBucket{rate: float64(rate) / float64(per), burst: max(burst, 1)}
+}
+
+type tokenBucket struct {
+	rate  float64 // Tokens per nanosecond.
+	burst int
+}
+
+func (a *tokenBucket) TTL() time.Duration {
+	return time.Duration(float64(a.burst) / a.rate)
+}
+
+func (a *tokenBucket) Take(state []byte, now time.Time) (Result, []byte) {
+	tokens, last := float64(a.burst), now.UnixNano()
+	if len(state) == 16 {
+		tokens = math.Float64frombits(binary.BigEndian.Uint64(state))
+		last = int64(binary.BigEndian.Uint64(state[8:]))
+	}
+	tokens = math.Min(float64(a.burst), tokens+float64(now.UnixNano()-last)*a.rate)
+
+	res := Result{Limit: a.burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - tokens) / a.rate))
+	}
+	res.Remaining = int(tokens)
+	res.Reset = time.Duration(math.Ceil((float64(a.burst) - tokens) / a.rate))
+
+	state = make([]byte, 16)
+	binary.BigEndian.PutUint64(state, math.Float64bits(tokens))
+	binary.BigEndian.PutUint64(state[8:], uint64(now.UnixNano()))
+	return res, state
+}
+
+// SlidingWindow returns an Algorithm allowing limit requests per window, estimating
+// the count over the sliding window from the counts of the current and previous
+// fixed windows. It panics unless limit and window are positive.
+func SlidingWindow(limit int, window time.Duration) Algorithm {
+	checkRate("SlidingWindow", limit, window)
+	return &slidingWindow{limit: limit, window: window}
+}
+
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+}
+
+func (a *slidingWindow) TTL() time.Duration {
+	return 2 * a.window
+}
+
+func (a *slidingWindow) Take(state []byte, now time.Time) (Result, []byte) {
+	w := int64(a.window)
+	start := now.UnixNano() - now.UnixNano()%w
+	var prev, curr int64
+	if len(state) == 24 {
+		stored := int64(binary.BigEndian.Uint64(state))
+		prev = int64(binary.BigEndian.Uint64(state[8:]))
+		curr = int64(binary.BigEndian.Uint64(state[16:]))
+		switch start - stored {
+		case 0:
+		case w:
+			prev, curr = curr, 0
+		default:
+			prev, curr = 0, 0
+		}
+	}
+
+	elapsed := now.UnixNano() - start
+	weight := 1 - float64(elapsed)/float64(w)
+	estimated := float64(prev)*weight + float64(curr)
+
+	res := Result{Limit: a.limit, Reset: time.Duration(w - elapsed)}
+	if estimated+1 <= float64(a.limit) {
+		curr++
+		estimated++
+		res.Allowed = true
+	} else if free := float64(a.limit-1) - float64(curr); free >= 0 && prev > 0 {
+		// Wait until the previous window's weight has decayed enough.
+		res.RetryAfter = time.Duration(math.Ceil((1-free/float64(prev))*float64(w))) - time.Duration(elapsed)
+	} else {
+		// Wait into the next window, where the current count becomes the previous one.
+		res.RetryAfter = res.Reset + time.Duration(math.Ceil((1-float64(a.limit-1)/float64(curr))*float64(w)))
+	}
+	res.Remaining = max(a.limit-int(math.Ceil(estimated)), 0)
+
+	state = make([]byte, 24)
+	binary.BigEndian.PutUint64(state, uint64(start))
+	binary.BigEndian.PutUint64(state[8:], uint64(prev))
+	binary.BigEndian.PutUint64(state[16:], uint64(curr))
+	return res, state
+}
+
+// GCRA returns an Algorithm implementing the Generic Cell Rate Algorithm, which
+// spaces requests evenly at rate requests per period while tolerating bursts of
+// up to burst requests. It panics unless rate and per are positive, and per is at
+// least rate nanoseconds.
+func GCRA(rate int, per time.Duration, burst int) Algorithm {
+	checkRate("GCRA", rate, per)
+	if per < time.Duration(rate) {
+		panic("ratelimit: GCRA rate exceeds one request per nanosecond")
+	}
+	return &gcra{interval: per / time.Duration(rate), burst: max(burst, 1)}
+}
+
+type gcra struct {
+	interval time.Duration // Emission interval between two requests.
+	burst    int
+}
+
+func (a *gcra) TTL() time.Duration {
+	return a.interval * time.Duration(a.burst)
+}
+
+func (a *gcra) Take(state []byte, now time.Time) (Result, []byte) {
+	t := now.UnixNano()
+	tat := t // Theoretical arrival time.
+	if len(state) == 8 {
+		tat = max(int64(binary.BigEndian.Uint64(state)), t)
+	}
+	tolerance := int64(a.interval) * int64(a.burst)
+	newTat := tat + int64(a.interval)
+
+	res := Result{Limit: a.burst}
+	if allowAt := newTat - tolerance; t < allowAt {
+		res.RetryAfter = time.Duration(allowAt - t)
+		newTat = tat
+	} else {
+		res.Allowed = true
+	}
+	res.Remaining = max(int((tolerance-(newTat-t))/int64(a.interval)), 0)
+	res.Reset = time.Duration(newTat - t)
+
+	state = make([]byte, 8)
+	binary.BigEndian.PutUint64(state, uint64(newTat))
+	return res, state
+}
+
+// checkRate panics unless an Algorithm is configured with a positive number of
+// requests over a positive period.
+func checkRate(algorithm string, n int, period time.Duration) {
+	if n <= 0 || period <= 0 {
+		panic("ratelimit: " + algorithm + " requires a positive number of requests and period")
+	}
+}
@@ -0,0 +1,120 @@
+// Package ratelimit provides a rate limiting middleware for Kokoro with pluggable
+// algorithms and stores.
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Abhishek2010dev/kokoro"
+)
+
+// ErrLimitExceeded is wrapped by the 429 *kokoro.HTTPError returned for limited requests.
+var ErrLimitExceeded = errors.New("ratelimit: limit exceeded")
+
+// KeyFunc returns the key a request is counted under.
+type KeyFunc func(c *kokoro.Context) string
+
+// ByIP counts requests per client IP, as reported by Context.RealIP.
+func ByIP(c *kokoro.Context) string {
+	return c.RealIP()
+}
+
+// ByHeader counts requests per value of the named header, e.g. an API key.
+// Requests without the header are counted per client IP, as by ByIP, rather than
+// sharing a single quota.
+func ByHeader(name string) KeyFunc {
+	return func(c *kokoro.Context) string {
+		if value := c.Header(name); value != "" {
+			return "header:" + value
+		}
+		return "ip:" + c.RealIP()
+	}
+}
+
+// ByRoute counts requests per route and client IP, so each route has its own quota.
+func ByRoute(c *kokoro.Context) string {
+	return c.Method() + " " + c.Route() + " " + c.RealIP()
+}
+
+// Config defines the configuration for the rate limiting middleware.
+type Config struct {
+	// Algorithm decides whether a request is allowed.
+	// Defaults to TokenBucket(60, time.Minute, 60).
+	Algorithm Algorithm
+
+	// Store keeps the per-key state. Defaults to a new MemoryStore.
+	Store Store
+
+	// Key returns the key requests are counted under. Defaults to ByIP.
+	Key KeyFunc
+
+	// Skip reports whether the request is exempt from limiting.
+	Skip func(c *kokoro.Context) bool
+
+	// DisableHeaders omits the RateLimit-Limit, RateLimit-Remaining and
+	// RateLimit-Reset headers from responses. Retry-After is always sent on 429.
+	DisableHeaders bool
+}
+
+// New creates a rate limiting middleware.
+//
+// Requests over the limit are answered with a 429 *kokoro.HTTPError, rendered by
+// the error handlers, along with a Retry-After header. Errors from the Store are
+// returned as-is.
+//
+// Example:
+//
+//	api.Use(ratelimit.New(ratelimit.Config{
+//	    Algorithm: ratelimit.GCRA(10, time.Second, 20),
+//	    Key:       ratelimit.ByHeader("X-API-Key"),
+//	}))
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Algorithm == nil {
+		cfg.Algorithm = TokenBucket(60, time.Minute, 60)
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.Key == nil {
+		cfg.Key = ByIP
+	}
+	ttl := cfg.Algorithm.TTL()
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			return next(c)
+		}
+
+		var res Result
+		err := cfg.Store.Update(cfg.Key(c), ttl, func(state []byte) []byte {
+			res, state = cfg.Algorithm.Take(state, time.Now())
+			return state
+		})
+		if err != nil {
+			return err
+		}
+
+		if !cfg.DisableHeaders {
+			c.SetHeader("RateLimit-Limit", strconv.Itoa(res.Limit))
+			c.SetHeader("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			c.SetHeader("RateLimit-Reset", seconds(res.Reset))
+		}
+		if !res.Allowed {
+			c.SetHeader(kokoro.HeaderRetryAfter, seconds(res.RetryAfter))
+			return kokoro.NewHTTPError(kokoro.StatusTooManyRequests).WithCause(ErrLimitExceeded)
+		}
+		return next(c)
+	}
+}
+
+// seconds formats a duration as a whole number of seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,270 @@
+package ratelimit
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAlgorithms(t *testing.T) {
+	type step struct {
+		at         time.Duration // Offset from the start of a window.
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name      string
+		algorithm Algorithm
+		steps     []step
+	}{
+		{"token bucket", TokenBucket(1, time.Second, 3), []step{
+			{0, true, 2, 0},
+			{0, true, 1, 0},
+			{0, true, 0, 0},
+			{0, false, 0, time.Second},
+			{time.Second, true, 0, 0},
+			{10 * time.Second, true, 2, 0},
+		}},
+		{"token bucket without burst", TokenBucket(2, time.Second, 0), []step{
+			{0, true, 0, 0},
+			{0, false, 0, 500 * time.Millisecond},
+			{500 * time.Millisecond, true, 0, 0},
+		}},
+		{"sliding window", SlidingWindow(2, time.Minute), []step{
+			{0, true, 1, 0},
+			{0, true, 0, 0},
+			{30 * time.Second, false, 0, time.Minute},
+			{90 * time.Second, true, 0, 0},
+			{90 * time.Second, false, 0, 30 * time.Second},
+			{120 * time.Second, true, 0, 0},
+			{5 * time.Minute, true, 1, 0},
+		}},
+		{"gcra", GCRA(1, time.Second, 3), []step{
+			{0, true, 2, 0},
+			{0, true, 1, 0},
+			{0, true, 0, 0},
+			{0, false, 0, time.Second},
+			{time.Second, true, 0, 0},
+			{time.Second, false, 0, time.Second},
+			{10 * time.Second, true, 2, 0},
+		}},
+	}
+	start := time.Unix(600, 0) // Aligned on minutes, as sliding windows are.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state []byte
+			for i, s := range tt.steps {
+				var res Result
+				res, state = tt.algorithm.Take(state, start.Add(s.at))
+				if res.Allowed != s.allowed || res.Remaining != s.remaining || res.RetryAfter != s.retryAfter {
+					t.Fatalf("step %d at %v: allowed %v, remaining %d, retry after %v; want %v, %d, %v",
+						i, s.at, res.Allowed, res.Remaining, res.RetryAfter, s.allowed, s.remaining, s.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestAlgorithmsResetMalformedState(t *testing.T) {
+	for name, a := range map[string]Algorithm{
+		"token bucket":   TokenBucket(1, time.Second, 1),
+		"sliding window": SlidingWindow(1, time.Second),
+		"gcra":           GCRA(1, time.Second, 1),
+	} {
+		// A state of another algorithm, e.g. after a configuration change.
+		state := binary.BigEndian.AppendUint32(nil, 42)
+		if res, _ := a.Take(state, time.Now()); !res.Allowed {
+			t.Errorf("%s: malformed state denied the request", name)
+		}
+	}
+}
+
+func TestAlgorithmsPanic(t *testing.T) {
+	for name, build := range map[string]func(){
+		"token bucket rate":   func() { TokenBucket(0, time.Second, 1) },
+		"token bucket period": func() { TokenBucket(1, 0, 1) },
+		"sliding window":      func() { SlidingWindow(-1, time.Second) },
+		"gcra period":         func() { GCRA(1, -time.Second, 1) },
+		"gcra too fast":       func() { GCRA(10, 5*time.Nanosecond, 1) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("constructor did not panic")
+				}
+			}()
+			build()
+		})
+	}
+}
+
+// failingStore is a Store whose updates always fail.
+type failingStore struct{ err error }
+
+func (s failingStore) Update(string, time.Duration, func([]byte) []byte) error { return s.err }
+
+func TestRateLimit(t *testing.T) {
+	errStore := errors.New("store unavailable")
+	tests := []struct {
+		name     string
+		cfg      Config
+		requests [][2]string // Remote IP and X-API-Key of each request.
+		status   []int
+		headers  bool
+		cause    error
+	}{
+		{"within limit", Config{Algorithm: TokenBucket(1, time.Hour, 2)},
+			[][2]string{{"10.0.0.1", ""}, {"10.0.0.1", ""}},
+			[]int{kokoro.StatusOK, kokoro.StatusOK}, true, nil},
+		{"limited", Config{Algorithm: TokenBucket(1, time.Hour, 2)},
+			[][2]string{{"10.0.0.1", ""}, {"10.0.0.1", ""}, {"10.0.0.1", ""}},
+			[]int{kokoro.StatusOK, kokoro.StatusOK, kokoro.StatusTooManyRequests}, true, ErrLimitExceeded},
+		{"per ip", Config{Algorithm: TokenBucket(1, time.Hour, 1)},
+			[][2]string{{"10.0.0.1", ""}, {"10.0.0.2", ""}, {"10.0.0.1", ""}},
+			[]int{kokoro.StatusOK, kokoro.StatusOK, kokoro.StatusTooManyRequests}, true, ErrLimitExceeded},
+		{"by header", Config{Algorithm: TokenBucket(1, time.Hour, 1), Key: ByHeader("X-API-Key")},
+			[][2]string{{"10.0.0.1", "a"}, {"10.0.0.1", "b"}, {"10.0.0.2", "a"}},
+			[]int{kokoro.StatusOK, kokoro.StatusOK, kokoro.StatusTooManyRequests}, true, ErrLimitExceeded},
+		{"by header falls back to ip", Config{Algorithm: TokenBucket(1, time.Hour, 1), Key: ByHeader("X-API-Key")},
+			[][2]string{{"10.0.0.1", ""}, {"10.0.0.2", ""}, {"10.0.0.1", ""}},
+			[]int{kokoro.StatusOK, kokoro.StatusOK, kokoro.StatusTooManyRequests}, true, ErrLimitExceeded},
+		{"header value cannot impersonate ip", Config{Algorithm: TokenBucket(1, time.Hour, 1), Key: ByHeader("X-API-Key")},
+			[][2]string{{"10.0.0.1", ""}, {"10.0.0.2", "10.0.0.1"}},
+			[]int{kokoro.StatusOK, kokoro.StatusOK}, true, nil},
+		{"skipped", Config{Algorithm: TokenBucket(1, time.Hour, 1), Skip: func(c *kokoro.Context) bool { return true }},
+			[][2]string{{"10.0.0.1", ""}, {"10.0.0.1", ""}},
+			[]int{kokoro.StatusOK, kokoro.StatusOK}, false, nil},
+		{"headers disabled", Config{Algorithm: TokenBucket(1, time.Hour, 1), DisableHeaders: true},
+			[][2]string{{"10.0.0.1", ""}, {"10.0.0.1", ""}},
+			[]int{kokoro.StatusOK, kokoro.StatusTooManyRequests}, false, ErrLimitExceeded},
+		{"store error", Config{Store: failingStore{errStore}},
+			[][2]string{{"10.0.0.1", ""}},
+			[]int{kokoro.StatusInternalServerError}, false, errStore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled error
+			s := kokoro.New()
+			s.SetErrorHandler(func(c *kokoro.Context, err error) error {
+				handled = err
+				code := kokoro.StatusInternalServerError
+				var he *kokoro.HTTPError
+				if errors.As(err, &he) {
+					code = he.Code
+				}
+				return c.SendStatusCode(code)
+			})
+			s.Use(New(tt.cfg))
+			s.GET("/", func(c *kokoro.Context) error { return c.SendText("ok") })
+
+			var ctx fasthttp.RequestCtx
+			for i, r := range tt.requests {
+				handled = nil
+				ctx.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.ParseIP(r[0]), Port: 1234}, nil)
+				ctx.Request.SetRequestURI("/")
+				if r[1] != "" {
+					ctx.Request.Header.Set("X-API-Key", r[1])
+				}
+				s.Handler(&ctx)
+
+				if got := ctx.Response.StatusCode(); got != tt.status[i] {
+					t.Fatalf("request %d: status = %d, want %d (error %v)", i, got, tt.status[i], handled)
+				}
+			}
+			if tt.cause != nil && !errors.Is(handled, tt.cause) {
+				t.Errorf("error = %v, want %v", handled, tt.cause)
+			}
+			last := tt.status[len(tt.status)-1]
+			if got := len(ctx.Response.Header.Peek("RateLimit-Limit")) > 0; got != tt.headers {
+				t.Errorf("RateLimit headers sent = %v, want %v", got, tt.headers)
+			}
+			if got := len(ctx.Response.Header.Peek(kokoro.HeaderRetryAfter)) > 0; got != (last == kokoro.StatusTooManyRequests) {
+				t.Errorf("Retry-After = %q on a %d", ctx.Response.Header.Peek(kokoro.HeaderRetryAfter), last)
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	s := kokoro.New()
+	s.Use(New(Config{Algorithm: TokenBucket(1, 10*time.Second, 2)}))
+	s.GET("/", func(c *kokoro.Context) error { return nil })
+
+	want := []struct{ status, remaining, reset, retryAfter string }{
+		{"200", "1", "10", ""},
+		{"200", "0", "20", ""},
+		{"429", "0", "20", "10"},
+	}
+	for i, w := range want {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI("/")
+		s.Handler(&ctx)
+
+		h := &ctx.Response.Header
+		got := []string{string(h.Peek("RateLimit-Limit")), string(h.Peek("RateLimit-Remaining")), string(h.Peek("RateLimit-Reset")), string(h.Peek(kokoro.HeaderRetryAfter))}
+		if got[0] != "2" || got[1] != w.remaining || got[2] != w.reset || got[3] != w.retryAfter {
+			t.Errorf("request %d: limit, remaining, reset, retry after = %q, want [2 %s %s %s]", i, got, w.remaining, w.reset, w.retryAfter)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	count := func(key string, ttl time.Duration) int {
+		var n int
+		s.Update(key, ttl, func(state []byte) []byte {
+			n = len(state) + 1
+			return make([]byte, n)
+		})
+		return n
+	}
+
+	if got := count("a", time.Hour); got != 1 {
+		t.Errorf("new key: count = %d, want 1", got)
+	}
+	if got := count("a", time.Hour); got != 2 {
+		t.Errorf("existing key: count = %d, want 2", got)
+	}
+	if got := count("b", time.Hour); got != 1 {
+		t.Errorf("other key: count = %d, want 1", got)
+	}
+	count("c", -time.Second)
+	if got := count("c", time.Hour); got != 1 {
+		t.Errorf("expired key: count = %d, want 1", got)
+	}
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count("concurrent", time.Hour)
+		}()
+	}
+	wg.Wait()
+	if got := count("concurrent", time.Hour); got != 101 {
+		t.Errorf("concurrent updates: count = %d, want 101", got)
+	}
+}
+
+func TestMemoryStoreSweepsExpiredKeys(t *testing.T) {
+	s := NewMemoryStore()
+	for i := range memoryShards * sweepEvery {
+		key := string(rune(i))
+		s.Update(key, -time.Second, func([]byte) []byte { return nil })
+	}
+	var entries int
+	for i := range s.shards {
+		entries += len(s.shards[i].entries)
+	}
+	if entries >= memoryShards*sweepEvery {
+		t.Errorf("%d expired entries kept, want them swept", entries)
+	}
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Store keeps the per-key state of an Algorithm. Implementations backed by shared
+// storage, such as Redis, allow limits to be enforced across several servers.
+type Store interface {
+	// Update atomically replaces the state stored under key with the one returned
+	// by fn, which receives nil for unknown or expired keys. The new state expires
+	// after ttl.
+	Update(key string, ttl time.Duration, fn func(state []byte) []byte) error
+}
+
+// memoryShards is the number of independently locked shards of a MemoryStore.
+const memoryShards = 64
+
+// sweepEvery is the number of updates of a shard between two sweeps of its expired keys.
+const sweepEvery = 1024
+
+// MemoryStore is an in-process Store, sharded to reduce lock contention.
+// Expired keys are removed incrementally as the store is updated, so it needs
+// no background goroutine.
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [memoryShards]memoryShard
+}
+
+type memoryShard struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	updates int
+}
+
+type memoryEntry struct {
+	state   []byte
+	expires int64
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]memoryEntry)
+	}
+	return s
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(key string, ttl time.Duration, fn func(state []byte) []byte) error {
+	shard := &s.shards[maphash.String(s.seed, key)%memoryShards]
+	now := time.Now().UnixNano()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.updates++
+	if shard.updates%sweepEvery == 0 {
+		for k, e := range shard.entries {
+			if e.expires <= now {
+				delete(shard.entries, k)
+			}
+		}
+	}
+
+	var state []byte
+	if e, ok := shard.entries[key]; ok && e.expires > now {
+		state = e.state
+	}
+	shard.entries[key] = memoryEntry{state: fn(state), expires: now + int64(ttl)}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package requestid provides a middleware that assigns every request an ID,
+// propagated through the response, the request logger and outbound requests.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"github.com/Abhishek2010dev/kokoro"
+)
+
+// localKey is the Context local under which the request ID is stored.
+type localKey struct{}
+
+// Config defines the configuration for the request ID middleware.
+type Config struct {
+	// Header is the header the ID is read from and echoed in. Defaults to "X-Request-ID".
+	Header string
+
+	// Generator creates IDs for requests without a valid incoming one.
+	// Defaults to UUIDv7; ULID is also provided.
+	Generator func() string
+
+	// MaxLength is the maximum length of an accepted incoming ID. Defaults to 128.
+	MaxLength int
+
+	// Validate reports whether an incoming ID is accepted. Rejected IDs are replaced
+	// by a generated one. Defaults to accepting non-empty IDs of at most MaxLength
+	// characters made of letters, digits and "-_.:+/=".
+	Validate func(id string) bool
+
+	// LogKey is the attribute key added to the request's logger. Defaults to "request_id".
+	LogKey string
+}
+
+// New creates a request ID middleware.
+//
+// The ID is stored on the Context (see FromContext), echoed in the response header,
+// added to the Context.Logger and propagated to outbound requests made with Context.Do.
+//
+// Example:
+//
+//	s.Use(requestid.New(requestid.Config{Generator: requestid.ULID}))
+func New(config ...Config) kokoro.NextMiddleware {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Header == "" {
+		cfg.Header = kokoro.HeaderXRequestID
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = UUIDv7
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 128
+	}
+	if cfg.Validate == nil {
+		cfg.Validate = func(id string) bool {
+			return validID(id, cfg.MaxLength)
+		}
+	}
+	if cfg.LogKey == "" {
+		cfg.LogKey = "request_id"
+	}
+
+	return func(c *kokoro.Context, next kokoro.HandlerFunc) error {
+		id := c.Header(cfg.Header)
+		if !cfg.Validate(id) {
+			id = cfg.Generator()
+		}
+
+		c.SetLocal(localKey{}, id)
+		c.SetHeader(cfg.Header, id)
+		c.SetLogger(c.Logger().With(cfg.LogKey, id))
+		c.PropagateHeader(cfg.Header, id)
+		return next(c)
+	}
+}
+
+// FromContext returns the ID assigned to the request, or "" if the middleware did not run.
+func FromContext(c *kokoro.Context) string {
+	id, _ := c.Local(localKey{}).(string)
+	return id
+}
+
+// validID reports whether id is non-empty, at most maxLength long and made only
+// of characters safe to log and echo.
+func validID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		b := id[i]
+		switch {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		case b == '-', b == '_', b == '.', b == ':', b == '+', b == '/', b == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// UUIDv7 generates a time-ordered UUID version 7 (RFC 9562) in its canonical textual form.
+func UUIDv7() string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+	ms := uint64(time.Now().UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(u[2:], uint32(ms))
+	u[6] = u[6]&0x0f | 0x70 // version 7
+	u[8] = u[8]&0x3f | 0x80 // variant 10
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates a Universally Unique Lexicographically Sortable Identifier:
+// a 48-bit millisecond timestamp followed by 80 random bits, as 26 base32 characters.
+func ULID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+	ms := uint64(time.Now().UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(u[2:], uint32(ms))
+
+	// Encode the 128 bits as 26 5-bit groups, the first group holding the top 3 bits.
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	var buf [26]byte
+	for i := 25; i >= 0; i-- {
+		buf[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
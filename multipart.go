@@ -0,0 +1,195 @@
+package kokoro
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+)
+
+// Errors wrapped by the *HTTPError values returned by MultipartReader and Part, for
+// use with errors.Is.
+var (
+	ErrNotMultipart      = errors.New("request is not multipart/form-data")
+	ErrMultipartTooLarge = errors.New("multipart body too large")
+	ErrPartTooLarge      = errors.New("multipart part too large")
+	ErrTooManyParts      = errors.New("too many multipart parts")
+)
+
+// MultipartConfig limits what a MultipartReader accepts. Zero values mean no limit.
+type MultipartConfig struct {
+	MaxPartSize  int64 // Maximum size of a single part's content, in bytes.
+	MaxTotalSize int64 // Maximum size of the whole request body, in bytes.
+	MaxParts     int   // Maximum number of parts.
+}
+
+// MultipartReader reads a multipart/form-data request body part by part, without
+// buffering files in memory or on disk as FormFile and MultipartForm do.
+type MultipartReader struct {
+	r     *multipart.Reader
+	cfg   MultipartConfig
+	parts int
+}
+
+// Part is a single part of a multipart body. It is an io.Reader over the part's
+// content, valid until the next call to NextPart.
+type Part struct {
+	part *multipart.Part
+	r    *bufio.Reader
+}
+
+// MultipartReader returns a reader streaming the parts of a multipart/form-data
+// request body. It returns a 415 *HTTPError when the request is not multipart.
+//
+// To avoid buffering large uploads before the handler runs, set the Server's
+// StreamRequestBody option; otherwise fasthttp reads the whole body first, within
+// its MaxRequestBodySize. The body is then only streamed if nothing read it
+// before: PostBody, Bind and the form methods load it into memory, and so do
+// middlewares that need it whole, such as decompress for encoded bodies.
+//
+// Example:
+//
+//	mr, err := c.MultipartReader(kokoro.MultipartConfig{MaxPartSize: 5 << 30})
+//	if err != nil {
+//	    return err
+//	}
+//	for {
+//	    part, err := mr.NextPart()
+//	    if err == io.EOF {
+//	        break
+//	    } else if err != nil {
+//	        return err
+//	    }
+//	    if part.FileName() != "" {
+//	        if _, err := part.SaveTo(dst); err != nil {
+//	            return err
+//	        }
+//	    }
+//	}
+func (c *Context) MultipartReader(config ...MultipartConfig) (*MultipartReader, error) {
+	cfg := MultipartConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	boundary := string(c.ctx.Request.Header.MultipartFormBoundary())
+	if boundary == "" {
+		return nil, NewHTTPError(StatusUnsupportedMediaType, "Expected a multipart/form-data body").WithCause(ErrNotMultipart)
+	}
+
+	var body io.Reader
+	if c.ctx.Request.IsBodyStream() {
+		body = c.ctx.Request.BodyStream()
+	} else {
+		body = bytes.NewReader(c.ctx.Request.Body())
+	}
+	if cfg.MaxTotalSize > 0 {
+		body = LimitReader(body, cfg.MaxTotalSize, NewHTTPError(StatusPayloadTooLarge,
+			"Request body exceeds "+strconv.FormatInt(cfg.MaxTotalSize, 10)+" bytes").WithCause(ErrMultipartTooLarge))
+	}
+	return &MultipartReader{r: multipart.NewReader(body, boundary), cfg: cfg}, nil
+}
+
+// NextPart returns the next part of the body, or io.EOF when there are no more parts.
+// The remaining content of the previous part is skipped. Exceeded limits are reported
+// as 413 *HTTPError values and malformed bodies as 400 ones.
+func (mr *MultipartReader) NextPart() (*Part, error) {
+	p, err := mr.r.NextPart()
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		var he *HTTPError
+		if errors.As(err, &he) {
+			return nil, he
+		}
+		return nil, NewHTTPError(StatusBadRequest, "Malformed multipart body").WithCause(err)
+	}
+
+	mr.parts++
+	if mr.cfg.MaxParts > 0 && mr.parts > mr.cfg.MaxParts {
+		return nil, NewHTTPError(StatusPayloadTooLarge,
+			"Multipart body exceeds "+strconv.Itoa(mr.cfg.MaxParts)+" parts").WithCause(ErrTooManyParts)
+	}
+
+	var content io.Reader = p
+	if mr.cfg.MaxPartSize > 0 {
+		content = LimitReader(p, mr.cfg.MaxPartSize, NewHTTPError(StatusPayloadTooLarge,
+			"Multipart part exceeds "+strconv.FormatInt(mr.cfg.MaxPartSize, 10)+" bytes").WithCause(ErrPartTooLarge))
+	}
+	return &Part{part: p, r: bufio.NewReaderSize(content, 512)}, nil
+}
+
+// FormName returns the name of the form field the part belongs to.
+func (p *Part) FormName() string {
+	return p.part.FormName()
+}
+
+// FileName returns the name of the uploaded file, reduced to a bare file name so it
+// can safely be used in a path, or "" when the part is not a file.
+func (p *Part) FileName() string {
+	return sanitizeFilename(p.part.FileName())
+}
+
+// Header returns the MIME header of the part.
+func (p *Part) Header() textproto.MIMEHeader {
+	return p.part.Header
+}
+
+// ContentType returns the Content-Type declared by the client for the part. It is
+// not to be trusted; see DetectContentType.
+func (p *Part) ContentType() string {
+	return p.part.Header.Get(HeaderContentType)
+}
+
+// DetectContentType sniffs the content type of the part from its first 512 bytes,
+// using http.DetectContentType, without consuming them. It must be called before
+// reading from the part.
+func (p *Part) DetectContentType() string {
+	head, _ := p.r.Peek(512)
+	return http.DetectContentType(head)
+}
+
+// Read reads the content of the part. It returns a 413 *HTTPError once the part
+// or the whole body exceeds its limit.
+func (p *Part) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// SaveTo streams the content of the part to w and returns the number of bytes written.
+func (p *Part) SaveTo(w io.Writer) (int64, error) {
+	return io.Copy(w, p.r)
+}
+
+// LimitReader returns a Reader that reads at most n bytes from r. Unlike
+// io.LimitReader, which reports io.EOF at the limit, it returns err once r has
+// more to read, so content over the limit is rejected rather than truncated.
+func LimitReader(r io.Reader, n int64, err error) io.Reader {
+	return &limitedReader{r: r, n: n, err: err}
+}
+
+// limitedReader is the Reader returned by LimitReader.
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// Read one byte past the limit to tell whether the content exceeds it.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		n = int(l.n)
+		l.n = 0
+		return n, l.err
+	}
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,156 @@
+package kokoro
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// multipartBody encodes fields, given as name/value pairs, into a multipart body.
+// Names starting with "file:" are sent as files named by the rest of the name.
+func multipartBody(t *testing.T, fields ...string) (string, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for i := 0; i+1 < len(fields); i += 2 {
+		var part io.Writer
+		var err error
+		if file, ok := strings.CutPrefix(fields[i], "file:"); ok {
+			part, err = w.CreateFormFile("upload", file)
+		} else {
+			part, err = w.CreateFormField(fields[i])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(part, fields[i+1])
+	}
+	w.Close()
+	return w.FormDataContentType(), buf.Bytes()
+}
+
+func TestMultipartReader(t *testing.T) {
+	big := strings.Repeat("x", 100)
+	tests := []struct {
+		name   string
+		cfg    MultipartConfig
+		fields []string
+		raw    string // Body sent instead of fields, with its Content-Type.
+		status int
+		body   string
+		cause  error
+	}{
+		{"fields and file", MultipartConfig{}, []string{"a", "1", "b", "2", "file:notes.txt", "hello"}, "", StatusOK, "a=1 b=2 upload[notes.txt]=hello ", nil},
+		{"file name sanitized", MultipartConfig{}, []string{"file:../../etc/passwd", "x"}, "", StatusOK, "upload[passwd]=x ", nil},
+		{"within limits", MultipartConfig{MaxPartSize: 100, MaxTotalSize: 1 << 10, MaxParts: 2}, []string{"a", big, "b", big}, "", StatusOK, "a=" + big + " b=" + big + " ", nil},
+		{"part too large", MultipartConfig{MaxPartSize: 99}, []string{"a", big}, "", StatusPayloadTooLarge, "", ErrPartTooLarge},
+		{"body too large", MultipartConfig{MaxTotalSize: 150}, []string{"a", big, "b", big}, "", StatusPayloadTooLarge, "", ErrMultipartTooLarge},
+		{"too many parts", MultipartConfig{MaxParts: 2}, []string{"a", "1", "b", "2", "c", "3"}, "", StatusPayloadTooLarge, "", ErrTooManyParts},
+		{"not multipart", MultipartConfig{}, nil, "text/plain\na=1", StatusUnsupportedMediaType, "", ErrNotMultipart},
+		{"malformed", MultipartConfig{}, nil, "multipart/form-data; boundary=x\n--x\r\nbroken", StatusBadRequest, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled error
+			s := New()
+			s.SetErrorHandler(func(c *Context, err error) error {
+				handled = err
+				return defaultErrorHandler(c, err)
+			})
+			s.POST("/", func(c *Context) error {
+				mr, err := c.MultipartReader(tt.cfg)
+				if err != nil {
+					return err
+				}
+				var out strings.Builder
+				for {
+					part, err := mr.NextPart()
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						return err
+					}
+					content, err := io.ReadAll(part)
+					if err != nil {
+						return err
+					}
+					out.WriteString(part.FormName())
+					if name := part.FileName(); name != "" {
+						out.WriteString("[" + name + "]")
+					}
+					out.WriteString("=" + string(content) + " ")
+				}
+				return c.SendText(out.String())
+			})
+
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+			ctx.Request.SetRequestURI("/")
+			if contentType, body, ok := strings.Cut(tt.raw, "\n"); ok {
+				ctx.Request.Header.SetContentType(contentType)
+				ctx.Request.SetBodyString(body)
+			} else {
+				contentType, body := multipartBody(t, tt.fields...)
+				ctx.Request.Header.SetContentType(contentType)
+				ctx.Request.SetBody(body)
+			}
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d (error %v)", got, tt.status, handled)
+			}
+			if tt.cause != nil && !errors.Is(handled, tt.cause) {
+				t.Errorf("error = %v, want %v", handled, tt.cause)
+			}
+			if tt.status == StatusOK {
+				if got := string(ctx.Response.Body()); got != tt.body {
+					t.Errorf("body = %q, want %q", got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	errLimit := errors.New("limit")
+	tests := []struct {
+		name    string
+		content string
+		n       int64
+		want    string
+		err     error
+	}{
+		{"under", "abc", 5, "abc", nil},
+		{"exact", "abcde", 5, "abcde", nil},
+		{"over", "abcdef", 5, "abcde", errLimit},
+		{"zero", "a", 0, "", errLimit},
+		{"empty", "", 0, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte at a time, so the limit is hit across reads.
+			got, err := io.ReadAll(LimitReader(oneByteReader{strings.NewReader(tt.content)}, tt.n, errLimit))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// oneByteReader reads at most one byte at a time from r.
+type oneByteReader struct{ r io.Reader }
+
+func (o oneByteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return o.r.Read(p)
+}
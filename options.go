@@ -0,0 +1,90 @@
+package kokoro
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// addAutoOptions registers the automatic OPTIONS response for a route pattern in a
+// router shadowing r, so OPTIONS requests, including CORS preflights, run through
+// the middlewares of the route. The first route registered for a pattern wins.
+//
+// The underlying router allows patterns whose params conflict, e.g. "/users/{id}"
+// and "/users/{name}", under different methods, but not under the same one. Such
+// patterns are registered in additional shadow routers, looked up in order.
+func (s *Server) addAutoOptions(r *Router, pattern, path string, mws []middlewareFunc) {
+	if s.autoOptions == nil {
+		s.autoOptions = make(map[*router.Router][]*router.Router)
+	}
+	shadows := s.autoOptions[r.r]
+	for _, shadow := range shadows {
+		if slices.Contains(shadow.List()[MethodOptions], path) {
+			return
+		}
+	}
+
+	handler := s.wrapGroup(r, pattern, chainMiddlewares(answerOptions, mws...))
+	for _, shadow := range shadows {
+		if handleShadow(shadow, path, handler) {
+			return
+		}
+	}
+	shadow := router.New()
+	shadow.Handle(MethodOptions, path, handler)
+	s.autoOptions[r.r] = append(shadows, shadow)
+}
+
+// handleShadow registers handler for OPTIONS requests to path in shadow, and
+// reports false if path conflicts with a pattern already registered there.
+func handleShadow(shadow *router.Router, path string, handler fasthttp.RequestHandler) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	shadow.Handle(MethodOptions, path, handler)
+	return true
+}
+
+// answerOptions answers an OPTIONS request with the methods allowed for the path.
+func answerOptions(c *Context) error {
+	c.ctx.Response.Header.Set(HeaderAllow, allowedMethods(c.group.r, c.server.BytesToString(c.ctx.Request.URI().PathOriginal())))
+	c.setAllow()
+	return c.SendStatusCode(StatusNoContent)
+}
+
+// allowedMethods returns the sorted, comma-separated methods with a route matching the path.
+func allowedMethods(r *router.Router, path string) string {
+	methods := []string{MethodOptions}
+	for method := range r.List() {
+		if method == MethodOptions {
+			continue
+		}
+		if h, _ := r.Lookup(method, path, nil); h != nil {
+			methods = append(methods, method)
+		}
+	}
+	slices.Sort(methods)
+	return strings.Join(methods, ", ")
+}
+
+// lookupOptions returns the automatic OPTIONS handler for the path, or nil if
+// the path has an explicit OPTIONS route or no route at all.
+func (s *Server) lookupOptions(r *router.Router, path string, fctx *fasthttp.RequestCtx) fasthttp.RequestHandler {
+	shadows := s.autoOptions[r]
+	if len(shadows) == 0 {
+		return nil
+	}
+	if h, _ := r.Lookup(MethodOptions, path, nil); h != nil {
+		return nil
+	}
+	for _, shadow := range shadows {
+		if h, _ := shadow.Lookup(MethodOptions, path, fctx); h != nil {
+			return h
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package kokoro
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestHeadAndOptions(t *testing.T) {
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) error {
+			c.SetHeader("X-Handler", name)
+			return c.SendText(name)
+		}
+	}
+
+	s := New()
+	s.GET("/users", mark("list"))
+	s.POST("/users", mark("create"))
+	s.GET("/users/{id:int}", mark("show"))
+	s.DELETE("/users/{id:int}", mark("delete"))
+	s.GET("/report", mark("report"))
+	s.HEAD("/report", mark("report head"))
+	s.PUT("/custom", mark("put"))
+	s.OPTIONS("/custom", mark("custom options"))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		status  int
+		handler string
+		allow   string
+	}{
+		{"get", MethodGet, "/users", StatusOK, "list", ""},
+		{"head from get", MethodHead, "/users", StatusOK, "list", ""},
+		{"head with params", MethodHead, "/users/7", StatusOK, "show", ""},
+		{"explicit head", MethodHead, "/report", StatusOK, "report head", ""},
+		{"head without get", MethodHead, "/custom", StatusMethodNotAllowed, "", "OPTIONS, PUT"},
+		{"options", MethodOptions, "/users", StatusNoContent, "", "GET, HEAD, OPTIONS, POST"},
+		{"options with params", MethodOptions, "/users/7", StatusNoContent, "", "DELETE, GET, HEAD, OPTIONS"},
+		{"options constraint mismatch", MethodOptions, "/users/abc", StatusNotFound, "", ""},
+		{"explicit options", MethodOptions, "/custom", StatusOK, "custom options", ""},
+		{"options unknown path", MethodOptions, "/missing", StatusNotFound, "", ""},
+		{"method not allowed", MethodPut, "/users", StatusMethodNotAllowed, "", "GET, HEAD, OPTIONS, POST"},
+		{"method not allowed with params", MethodPost, "/users/7", StatusMethodNotAllowed, "", "DELETE, GET, HEAD, OPTIONS"},
+		{"head unknown path", MethodHead, "/missing", StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(tt.method)
+			ctx.Request.SetRequestURI(tt.path)
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if got := string(ctx.Response.Header.Peek("X-Handler")); got != tt.handler {
+				t.Errorf("handler = %q, want %q", got, tt.handler)
+			}
+			if got := string(ctx.Response.Header.Peek(HeaderAllow)); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+		})
+	}
+}
+
+// TestOptionsRunsRouteMiddlewares checks that automatic OPTIONS responses, such as
+// CORS preflights, go through the middlewares of the route they describe.
+func TestOptionsRunsRouteMiddlewares(t *testing.T) {
+	s := New()
+	s.GET("/users/{id}", func(c *Context) error { return nil }, func(c *Context, next HandlerFunc) error {
+		c.SetHeader("X-Middleware", "route")
+		return next(c)
+	})
+	s.GET("/plain", func(c *Context) error { return nil })
+
+	for path, want := range map[string]string{"/users/1": "route", "/plain": ""} {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod(MethodOptions)
+		ctx.Request.SetRequestURI(path)
+		s.Handler(&ctx)
+
+		if got := string(ctx.Response.Header.Peek("X-Middleware")); got != want {
+			t.Errorf("OPTIONS %s: X-Middleware = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package kokoro
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// paramConstraints maps the named constraints usable in route patterns, such as
+// "/users/{id:int}", to the regular expressions enforced by the router.
+// Any other constraint, e.g. "{slug:[a-z-]+}", is used as a regular expression as-is.
+var paramConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"float": `-?[0-9]+(?:\.[0-9]+)?`,
+	"bool":  `true|false|1|0`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"hex":   `[0-9a-fA-F]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// expandParamConstraints rewrites named constraints in a route pattern into the
+// regular expressions understood by the underlying router, so "{id:int}" becomes
+// "{id:-?[0-9]+}". Requests whose segment does not satisfy a constraint do not
+// match the route and never reach its handler.
+func expandParamConstraints(path string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(path, '{')
+		if start == -1 {
+			break
+		}
+		end := paramEnd(path, start)
+		if end == -1 {
+			break
+		}
+
+		b.WriteString(path[:start])
+		param := path[start+1 : end]
+		if name, constraint, ok := strings.Cut(param, ":"); ok {
+			if pattern, known := paramConstraints[constraint]; known {
+				param = name + ":" + pattern
+			}
+		}
+		b.WriteByte('{')
+		b.WriteString(param)
+		b.WriteByte('}')
+		path = path[end+1:]
+	}
+	b.WriteString(path)
+	return b.String()
+}
+
+// paramEnd returns the index of the brace closing the param opened at start,
+// accounting for braces nested inside a regular expression, or -1 if unbalanced.
+func paramEnd(path string, start int) int {
+	depth := 0
+	for i := start; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ReversePath formats a route pattern into a path by substituting its params with
+// the values of the given name/value pairs. It only formats the pattern: whether a
+// route is registered with it is not checked, so pass the full pattern, group
+// prefix included, exactly as the route was registered.
+//
+// Values are formatted with fmt.Sprint and path-escaped; the slashes of catch-all
+// params ("{name:*}") are kept. Optional params ("{name?}") without a value are left
+// out along with their segment. An error is returned for missing params, unknown
+// names, and values not satisfying the param's constraint.
+//
+// Example:
+//
+//	path, err := kokoro.ReversePath("/users/{id:int}/posts/{slug}", "id", 42, "slug", "hello world")
+//	// path == "/users/42/posts/hello%20world"
+func ReversePath(pattern string, params ...any) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("kokoro: odd number of params for %q", pattern)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		name, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("kokoro: param name %v for %q is not a string", params[i], pattern)
+		}
+		values[name] = fmt.Sprint(params[i+1])
+	}
+
+	var b strings.Builder
+	used := 0
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start == -1 {
+			break
+		}
+		end := paramEnd(pattern, start)
+		if end == -1 {
+			break
+		}
+		b.WriteString(pattern[:start])
+		name, constraint, _ := strings.Cut(pattern[start+1:end], ":")
+		pattern = pattern[end+1:]
+
+		name, optional := strings.CutSuffix(name, "?")
+		value, ok := values[name]
+		if !ok {
+			if !optional {
+				return "", fmt.Errorf("kokoro: missing param %q", name)
+			}
+			// Drop the slash introducing the optional segment.
+			path := strings.TrimSuffix(b.String(), "/")
+			b.Reset()
+			b.WriteString(path)
+			continue
+		}
+		used++
+
+		switch constraint {
+		case "":
+			b.WriteString(url.PathEscape(value))
+		case "*":
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			b.WriteString(strings.Join(segments, "/"))
+		default:
+			if !constraintRegexp(constraint).MatchString(value) {
+				return "", fmt.Errorf("kokoro: param %q value %q does not satisfy %q", name, value, constraint)
+			}
+			b.WriteString(url.PathEscape(value))
+		}
+	}
+	b.WriteString(pattern)
+
+	if used != len(values) {
+		return "", fmt.Errorf("kokoro: unknown params for %q", b.String())
+	}
+	return b.String(), nil
+}
+
+// constraintRegexps caches the compiled regular expressions of param constraints.
+var constraintRegexps sync.Map
+
+// constraintRegexp returns the regular expression matching whole values satisfying
+// a named or regular expression constraint.
+func constraintRegexp(constraint string) *regexp.Regexp {
+	if re, ok := constraintRegexps.Load(constraint); ok {
+		return re.(*regexp.Regexp)
+	}
+	expr := constraint
+	if pattern, known := paramConstraints[constraint]; known {
+		expr = pattern
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		re = regexp.MustCompile(`^\b\B$`) // Matches nothing.
+	}
+	constraintRegexps.Store(constraint, re)
+	return re
+}
+
+// Param parses the path parameter named key into a value of type T.
+//
+// Supported types are string, bool, all integer and float kinds, time.Duration,
+// and any type whose pointer implements encoding.TextUnmarshaler.
+// A missing or malformed value results in a 400 *HTTPError, so handlers can
+// return the error directly:
+//
+//	id, err := kokoro.Param[int64](c, "id")
+//	if err != nil {
+//	    return err
+//	}
+func Param[T any](c *Context, key string) (T, error) {
+	var v T
+	if err := parseParam(&v, c.Param(key)); err != nil {
+		return v, &HTTPError{Code: StatusBadRequest, Message: fmt.Sprintf("invalid path parameter %q", key)}
+	}
+	return v, nil
+}
+
+// ParamInt retrieves a path parameter by its key and parses it as an int.
+// Returns a 400 *HTTPError if the value is missing or not a valid integer.
+func (c *Context) ParamInt(key string) (int, error) {
+	return Param[int](c, key)
+}
+
+// ParamUUID retrieves a path parameter by its key and validates it as a UUID
+// in its canonical 8-4-4-4-12 hexadecimal form. The value is returned lower-cased.
+// Returns a 400 *HTTPError if the value is missing or not a valid UUID.
+func (c *Context) ParamUUID(key string) (string, error) {
+	value := c.Param(key)
+	if !isUUID(value) {
+		return "", &HTTPError{Code: StatusBadRequest, Message: fmt.Sprintf("invalid path parameter %q", key)}
+	}
+	return strings.ToLower(value), nil
+}
+
+// isUUID reports whether s is a UUID in canonical textual form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isHexDigit reports whether b is an ASCII hexadecimal digit.
+func isHexDigit(b byte) bool {
+	return '0' <= b && b <= '9' || 'a' <= b && b <= 'f' || 'A' <= b && b <= 'F'
+}
+
+// parseParam parses the raw string value into the value pointed to by dst.
+func parseParam(dst any, value string) error {
+	if u, ok := dst.(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	var err error
+	switch d := dst.(type) {
+	case *string:
+		if value == "" {
+			return strconv.ErrSyntax
+		}
+		*d = value
+	case *bool:
+		*d, err = strconv.ParseBool(value)
+	case *int:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, strconv.IntSize)
+		*d = int(n)
+	case *int8:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 8)
+		*d = int8(n)
+	case *int16:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 16)
+		*d = int16(n)
+	case *int32:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 32)
+		*d = int32(n)
+	case *int64:
+		*d, err = strconv.ParseInt(value, 10, 64)
+	case *uint:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, strconv.IntSize)
+		*d = uint(n)
+	case *uint8:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 8)
+		*d = uint8(n)
+	case *uint16:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 16)
+		*d = uint16(n)
+	case *uint32:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 32)
+		*d = uint32(n)
+	case *uint64:
+		*d, err = strconv.ParseUint(value, 10, 64)
+	case *float32:
+		var f float64
+		f, err = strconv.ParseFloat(value, 32)
+		*d = float32(f)
+	case *float64:
+		*d, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		*d, err = time.ParseDuration(value)
+	default:
+		return fmt.Errorf("kokoro: unsupported parameter type %T", dst)
+	}
+	return err
+}
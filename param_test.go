@@ -0,0 +1,162 @@
+package kokoro
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestReversePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		params  []any
+		want    string
+		wantErr bool
+	}{
+		{"static", "/about", nil, "/about", false},
+		{"param", "/users/{id}", []any{"id", 42}, "/users/42", false},
+		{"escaped", "/tags/{tag}", []any{"tag", "a b/c"}, "/tags/a%20b%2Fc", false},
+		{"int", "/users/{id:int}", []any{"id", -7}, "/users/-7", false},
+		{"int mismatch", "/users/{id:int}", []any{"id", "abc"}, "", true},
+		{"uint mismatch", "/users/{id:uint}", []any{"id", -7}, "", true},
+		{"uuid", "/orders/{id:uuid}", []any{"id", "123e4567-e89b-12d3-a456-426614174000"}, "/orders/123e4567-e89b-12d3-a456-426614174000", false},
+		{"uuid mismatch", "/orders/{id:uuid}", []any{"id", "nope"}, "", true},
+		{"regexp", "/posts/{slug:[a-z]{2,3}}", []any{"slug", "abc"}, "/posts/abc", false},
+		{"regexp mismatch", "/posts/{slug:[a-z]{2,3}}", []any{"slug", "abcd"}, "", true},
+		{"wildcard keeps slashes", "/files/{path:*}", []any{"path", "docs/a b.txt"}, "/files/docs/a%20b.txt", false},
+		{"optional set", "/pages/{page?}", []any{"page", 2}, "/pages/2", false},
+		{"optional unset", "/pages/{page?}", nil, "/pages", false},
+		{"several", "/users/{id:int}/posts/{post}", []any{"post", "hi", "id", 1}, "/users/1/posts/hi", false},
+		{"missing", "/users/{id}", nil, "", true},
+		{"unknown", "/users/{id}", []any{"id", 1, "x", 2}, "", true},
+		{"odd", "/users/{id}", []any{"id"}, "", true},
+		{"name not a string", "/users/{id}", []any{1, 1}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReversePath(tt.pattern, tt.params...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReversePath(%q, %v) error = %v, want error %v", tt.pattern, tt.params, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ReversePath(%q, %v) = %q, want %q", tt.pattern, tt.params, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	s := New()
+	for _, pattern := range []string{
+		"/users/{id:int}",
+		"/pages/{n:uint}",
+		"/prices/{p:float}",
+		"/flags/{on:bool}",
+		"/names/{name:alpha}",
+		"/codes/{code:alnum}",
+		"/colors/{c:hex}",
+		"/orders/{id:uuid}",
+		"/posts/{slug:[a-z-]+}",
+		"/files/{path:*}",
+	} {
+		s.GET(pattern, func(c *Context) error { return c.SendText(pattern) })
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		route  string
+	}{
+		{"/users/42", StatusOK, "/users/{id:int}"},
+		{"/users/-42", StatusOK, "/users/{id:int}"},
+		{"/users/abc", StatusNotFound, ""},
+		{"/users/4.2", StatusNotFound, ""},
+		{"/pages/7", StatusOK, "/pages/{n:uint}"},
+		{"/pages/-7", StatusNotFound, ""},
+		{"/prices/9.99", StatusOK, "/prices/{p:float}"},
+		{"/prices/9.", StatusNotFound, ""},
+		{"/flags/true", StatusOK, "/flags/{on:bool}"},
+		{"/flags/yes", StatusNotFound, ""},
+		{"/names/Ada", StatusOK, "/names/{name:alpha}"},
+		{"/names/Ada1", StatusNotFound, ""},
+		{"/codes/Ab12", StatusOK, "/codes/{code:alnum}"},
+		{"/codes/Ab-12", StatusNotFound, ""},
+		{"/colors/ff00AA", StatusOK, "/colors/{c:hex}"},
+		{"/colors/fg", StatusNotFound, ""},
+		{"/orders/123e4567-e89b-12d3-a456-426614174000", StatusOK, "/orders/{id:uuid}"},
+		{"/orders/123e4567", StatusNotFound, ""},
+		{"/posts/hello-world", StatusOK, "/posts/{slug:[a-z-]+}"},
+		{"/posts/Hello", StatusNotFound, ""},
+		{"/files/a/b/c.txt", StatusOK, "/files/{path:*}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			ctx.Request.SetRequestURI(tt.path)
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if tt.status == StatusOK {
+				if got := string(ctx.Response.Body()); got != tt.route {
+					t.Errorf("matched %q, want %q", got, tt.route)
+				}
+			}
+		})
+	}
+}
+
+func TestParam(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		parse  func(c *Context) (any, error)
+		status int
+		want   string
+	}{
+		{"string", "ada", func(c *Context) (any, error) { return Param[string](c, "v") }, StatusOK, "ada"},
+		{"int", "-42", func(c *Context) (any, error) { return Param[int](c, "v") }, StatusOK, "-42"},
+		{"int invalid", "4x", func(c *Context) (any, error) { return Param[int](c, "v") }, StatusBadRequest, ""},
+		{"int8 overflow", "128", func(c *Context) (any, error) { return Param[int8](c, "v") }, StatusBadRequest, ""},
+		{"uint negative", "-1", func(c *Context) (any, error) { return Param[uint](c, "v") }, StatusBadRequest, ""},
+		{"uint16", "65535", func(c *Context) (any, error) { return Param[uint16](c, "v") }, StatusOK, "65535"},
+		{"float64", "2.5", func(c *Context) (any, error) { return Param[float64](c, "v") }, StatusOK, "2.5"},
+		{"bool", "true", func(c *Context) (any, error) { return Param[bool](c, "v") }, StatusOK, "true"},
+		{"bool invalid", "yes", func(c *Context) (any, error) { return Param[bool](c, "v") }, StatusBadRequest, ""},
+		{"duration", "1m30s", func(c *Context) (any, error) { return Param[time.Duration](c, "v") }, StatusOK, "1m30s"},
+		{"text unmarshaler", "2024-05-01T10:00:00Z", func(c *Context) (any, error) { return Param[time.Time](c, "v") }, StatusOK, "2024-05-01 10:00:00 +0000 UTC"},
+		{"text unmarshaler invalid", "yesterday", func(c *Context) (any, error) { return Param[time.Time](c, "v") }, StatusBadRequest, ""},
+		{"missing", "x", func(c *Context) (any, error) { return Param[int](c, "nope") }, StatusBadRequest, ""},
+		{"ParamInt", "7", func(c *Context) (any, error) { return c.ParamInt("v") }, StatusOK, "7"},
+		{"ParamUUID", "123E4567-E89B-12D3-A456-426614174000", func(c *Context) (any, error) { return c.ParamUUID("v") }, StatusOK, "123e4567-e89b-12d3-a456-426614174000"},
+		{"ParamUUID invalid", "123e4567-e89b-12d3-a456-42661417400g", func(c *Context) (any, error) { return c.ParamUUID("v") }, StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.GET("/{v}", func(c *Context) error {
+				v, err := tt.parse(c)
+				if err != nil {
+					return err
+				}
+				return c.SendText(fmt.Sprint(v))
+			})
+			var ctx fasthttp.RequestCtx
+			ctx.Request.SetRequestURI("/" + tt.value)
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if tt.status == StatusOK {
+				if got := string(ctx.Response.Body()); got != tt.want {
+					t.Errorf("value = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
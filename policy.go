@@ -0,0 +1,230 @@
+package kokoro
+
+import (
+	"path"
+	"strings"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// PathAction determines how a Router reacts when a request path only matches a
+// registered route after being corrected.
+type PathAction int
+
+const (
+	// PathRedirect redirects the client to the corrected path, with status 301
+	// for GET requests and 308 for all other methods so request bodies are preserved.
+	PathRedirect PathAction = iota
+
+	// PathTolerate serves the matching route directly, without redirecting.
+	PathTolerate
+
+	// PathStrict disables the correction; the request is answered with 404.
+	PathStrict
+)
+
+// PathPolicy configures how a Router group treats request paths that do not exactly
+// match a registered route. The zero value redirects for every correction, which
+// mirrors the defaults of the underlying fasthttp router.
+type PathPolicy struct {
+	// TrailingSlash handles paths that match a route once a trailing slash is
+	// added or removed, e.g. "/users/" for a route registered as "/users".
+	TrailingSlash PathAction
+
+	// CaseInsensitive handles paths whose static segments only match a route when
+	// compared case-insensitively, e.g. "/Users/42" for "/users/{id}".
+	// Parameter values keep their original case.
+	CaseInsensitive PathAction
+
+	// CleanPath handles paths that match a route once "." and ".." elements and
+	// repeated slashes are resolved, e.g. "/a//b/../c" for "/a/c".
+	CleanPath PathAction
+}
+
+// pathPolicyEntry binds a PathPolicy to the routes of a group below its prefix.
+type pathPolicyEntry struct {
+	router *router.Router
+	prefix string
+	policy PathPolicy
+}
+
+// SetPathPolicy sets the policy applied to requests below this Router's base path,
+// taking precedence over policies set on parent groups.
+//
+// Example:
+//
+//	api := s.Group("/api")
+//	api.SetPathPolicy(kokoro.PathPolicy{TrailingSlash: kokoro.PathTolerate, CaseInsensitive: kokoro.PathStrict})
+func (r *Router) SetPathPolicy(policy PathPolicy) *Router {
+	if r.server == nil {
+		panic("kokoro: SetPathPolicy requires a Router created by kokoro.New")
+	}
+	prefix := strings.TrimRight(r.basePath, "/")
+	for i, e := range r.server.pathPolicies {
+		if e.router == r.r && e.prefix == prefix {
+			r.server.pathPolicies[i].policy = policy
+			return r
+		}
+	}
+	r.server.pathPolicies = append(r.server.pathPolicies, pathPolicyEntry{router: r.r, prefix: prefix, policy: policy})
+	return r
+}
+
+// pathPolicy returns the policy of the most specific group containing the path.
+func (s *Server) pathPolicy(r *router.Router, p string) PathPolicy {
+	policy, longest := PathPolicy{}, -1
+	for _, e := range s.pathPolicies {
+		if e.router != r || len(e.prefix) <= longest || !hasPathPrefix(p, e.prefix) {
+			continue
+		}
+		policy, longest = e.policy, len(e.prefix)
+	}
+	return policy
+}
+
+// hasPathPrefix reports whether the path is the group prefix or lies below it,
+// comparing case-insensitively.
+func hasPathPrefix(p, prefix string) bool {
+	if len(p) < len(prefix) || !strings.EqualFold(p[:len(prefix)], prefix) {
+		return false
+	}
+	return len(p) == len(prefix) || p[len(prefix)] == '/'
+}
+
+// lookup finds the handler for the method and path, serving HEAD requests with
+// the GET route when no HEAD route is registered.
+func (s *Server) lookup(r *router.Router, method, p string, fctx *fasthttp.RequestCtx) fasthttp.RequestHandler {
+	h, _ := r.Lookup(method, p, fctx)
+	if h == nil && method == MethodHead {
+		h, _ = r.Lookup(MethodGet, p, fctx)
+	}
+	return h
+}
+
+// pathCandidate is a corrected request path along with whether reaching it
+// requires redirecting the client.
+type pathCandidate struct {
+	path     string
+	redirect bool
+}
+
+// fixPath applies the path policy to a request that matched no route. It either
+// serves or redirects to the corrected path and reports true, or reports false
+// when no correction leads to a registered route.
+func (s *Server) fixPath(r *router.Router, fctx *fasthttp.RequestCtx) bool {
+	method := string(fctx.Method())
+	p := string(fctx.Request.URI().PathOriginal())
+	if method == MethodConnect || p == "/" {
+		return false
+	}
+	policy := s.pathPolicy(r, p)
+
+	// Further corrections start from the cleaned path, so they can never produce
+	// paths such as "//host", which clients would resolve to another host.
+	base := pathCandidate{path: cleanPath(p)}
+	if base.path != p {
+		if policy.CleanPath == PathStrict {
+			return false
+		}
+		base.redirect = policy.CleanPath == PathRedirect
+	}
+	candidates := []pathCandidate{base}
+	if policy.TrailingSlash != PathStrict {
+		toggled := strings.TrimSuffix(base.path, "/")
+		if toggled == base.path {
+			toggled += "/"
+		}
+		if toggled != "" {
+			candidates = append(candidates, pathCandidate{toggled, base.redirect || policy.TrailingSlash == PathRedirect})
+		}
+	}
+	if policy.CaseInsensitive != PathStrict {
+		for _, c := range candidates {
+			if fixed, ok := foldRoute(r, method, c.path); ok && fixed != c.path {
+				candidates = append(candidates, pathCandidate{fixed, c.redirect || policy.CaseInsensitive == PathRedirect})
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		if c.path == p {
+			continue // The request path itself matched no route.
+		}
+		h := s.lookup(r, method, c.path, fctx)
+		if h == nil {
+			continue
+		}
+		if !c.redirect {
+			h(fctx)
+			return true
+		}
+		code := StatusMovedPermanently
+		if method != MethodGet {
+			code = StatusPermanentRedirect
+		}
+		// Redirect to an absolute URL on the request's own origin.
+		origin := s.proxies().resolve(fctx)
+		uri := origin.scheme + "://" + origin.host + c.path
+		if query := fctx.URI().QueryString(); len(query) > 0 {
+			uri += "?" + string(query)
+		}
+		fctx.Redirect(uri, code)
+		return true
+	}
+	return false
+}
+
+// cleanPath resolves "." and ".." elements and repeated slashes, keeping a trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if cleaned != "/" && strings.HasSuffix(p, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// foldRoute compares the path against the routes registered for the method,
+// treating static segments case-insensitively. It returns the path rewritten
+// with the static segments of the first matching route.
+func foldRoute(r *router.Router, method, p string) (string, bool) {
+	routes := r.List()
+	patterns := routes[method]
+	if method == MethodHead {
+		patterns = append(patterns[:len(patterns):len(patterns)], routes[MethodGet]...)
+	}
+
+	segments := strings.Split(p, "/")
+	for _, pattern := range patterns {
+		if fixed, ok := foldSegments(strings.Split(pattern, "/"), segments); ok {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+// foldSegments matches path segments against pattern segments. Segments holding
+// a param keep the request's value; a catch-all param consumes the remaining path.
+func foldSegments(pattern, segments []string) (string, bool) {
+	out := make([]string, 0, len(segments))
+	for i, seg := range pattern {
+		if strings.HasSuffix(seg, ":*}") && i <= len(segments) {
+			return strings.Join(append(out, segments[i:]...), "/"), true
+		}
+		if i >= len(segments) {
+			return "", false
+		}
+		if strings.Contains(seg, "{") {
+			out = append(out, segments[i])
+			continue
+		}
+		if !strings.EqualFold(seg, segments[i]) {
+			return "", false
+		}
+		out = append(out, seg)
+	}
+	if len(pattern) != len(segments) {
+		return "", false
+	}
+	return strings.Join(out, "/"), true
+}
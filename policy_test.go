@@ -0,0 +1,66 @@
+package kokoro
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestFixPath(t *testing.T) {
+	s := New()
+	ok := func(c *Context) error { return c.SendText(c.Route()) }
+	s.GET("/users", ok)
+	s.POST("/users", ok)
+	s.GET("/files/{a}/{b}", ok)
+	s.GET("/{a}/{b}", ok)
+	tolerant := s.Group("/tolerant")
+	tolerant.SetPathPolicy(PathPolicy{TrailingSlash: PathTolerate, CaseInsensitive: PathTolerate, CleanPath: PathTolerate})
+	tolerant.GET("/v1/items", ok)
+	strict := s.Group("/strict")
+	strict.SetPathPolicy(PathPolicy{TrailingSlash: PathStrict, CaseInsensitive: PathStrict, CleanPath: PathStrict})
+	strict.GET("/v1/items", ok)
+
+	tests := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{MethodGet, "/users", StatusOK, ""},
+		{MethodGet, "/users/", StatusMovedPermanently, "http://example.com/users"},
+		{MethodPost, "/users/", StatusPermanentRedirect, "http://example.com/users"},
+		{MethodGet, "/USERS", StatusMovedPermanently, "http://example.com/users"},
+		{MethodGet, "/Users/?q=1", StatusMovedPermanently, "http://example.com/users?q=1"},
+		{MethodGet, "/a/../users", StatusMovedPermanently, "http://example.com/users"},
+		{MethodGet, "/./users//", StatusMovedPermanently, "http://example.com/users"},
+		{MethodGet, "//users", StatusOK, ""}, // Normalized by fasthttp before routing.
+		{MethodGet, "/../../users", StatusMovedPermanently, "http://example.com/users"},
+		{MethodGet, "//evil.com/", StatusNotFound, ""},
+		{MethodGet, "//evil.com/x/", StatusMovedPermanently, "http://example.com/evil.com/x"},
+		{MethodGet, "///evil.com//x", StatusMovedPermanently, "http://example.com/evil.com/x"},
+		{MethodGet, "/FILES/a/b/", StatusMovedPermanently, "http://example.com/files/a/b"},
+		{MethodGet, "/../../etc/passwd/x", StatusNotFound, ""},
+		{MethodGet, "/tolerant/v1/items/", StatusOK, ""},
+		{MethodGet, "/tolerant/V1/ITEMS", StatusOK, ""},
+		{MethodGet, "/tolerant/v1//items", StatusOK, ""},
+		{MethodGet, "/strict/v1/items/", StatusNotFound, ""},
+		{MethodGet, "/strict/V1/ITEMS", StatusNotFound, ""},
+		{MethodGet, "/strict/v1//items", StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(tt.method)
+			ctx.Request.Header.SetHost("example.com")
+			ctx.Request.SetRequestURI(tt.path)
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Errorf("status = %d, want %d", got, tt.status)
+			}
+			if got := string(ctx.Response.Header.Peek(HeaderLocation)); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
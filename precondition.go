@@ -0,0 +1,109 @@
+package kokoro
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckPreconditions evaluates the request's conditional headers against the
+// current state of the target resource, in the order defined by RFC 9110,
+// Section 13.2.2, to protect it from lost updates.
+//
+// etag and lastModified describe the current representation; pass "" and the zero
+// time when the resource does not exist, so that "If-Match: *" fails and
+// "If-None-Match: *" succeeds. It returns a 412 Precondition Failed *HTTPError
+// when a precondition fails. For GET and HEAD requests whose cached copy is still
+// current, it answers 304 Not Modified itself and reports done, leaving nothing
+// for the handler to send.
+//
+// Example:
+//
+//	s.PUT("/docs/{id}", func(c *kokoro.Context) error {
+//	    doc := load(c.Param("id"))
+//	    if done, err := c.CheckPreconditions(doc.ETag, doc.UpdatedAt); done || err != nil {
+//	        return err
+//	    }
+//	    // ... apply the update
+//	})
+func (c *Context) CheckPreconditions(etag string, lastModified time.Time) (done bool, err error) {
+	exists := etag != "" || !lastModified.IsZero()
+	lastModified = lastModified.Truncate(time.Second)
+
+	// 1. If-Match, with the strong comparison.
+	if ifMatch := c.Header(HeaderIfMatch); ifMatch != "" {
+		if !matchesResource(ifMatch, etag, exists, true) {
+			return false, preconditionFailed("If-Match")
+		}
+	} else if ius := c.Header(HeaderIfUnmodifiedSince); ius != "" && !lastModified.IsZero() {
+		// 2. If-Unmodified-Since, only evaluated without If-Match.
+		if t, err := http.ParseTime(ius); err == nil && lastModified.After(t) {
+			return false, preconditionFailed("If-Unmodified-Since")
+		}
+	}
+
+	safe := c.Method() == MethodGet || c.Method() == MethodHead
+
+	// 3. If-None-Match, with the weak comparison.
+	if ifNoneMatch := c.Header(HeaderIfNoneMatch); ifNoneMatch != "" {
+		if matchesResource(ifNoneMatch, etag, exists, false) {
+			if safe {
+				return true, c.notModified(etag, lastModified)
+			}
+			return false, preconditionFailed("If-None-Match")
+		}
+	} else if ims := c.Header(HeaderIfModifiedSince); ims != "" && safe && !lastModified.IsZero() {
+		// 4. If-Modified-Since, only evaluated for GET and HEAD without If-None-Match.
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			return true, c.notModified(etag, lastModified)
+		}
+	}
+	return false, nil
+}
+
+// RequirePreconditions is like CheckPreconditions, but additionally rejects
+// requests with unsafe methods carrying neither If-Match nor If-Unmodified-Since
+// with 428 Precondition Required (RFC 6585, Section 3), forcing clients to prove
+// they are updating the current representation.
+func (c *Context) RequirePreconditions(etag string, lastModified time.Time) (done bool, err error) {
+	switch c.Method() {
+	case MethodGet, MethodHead, MethodOptions, MethodTrace:
+	default:
+		if c.Header(HeaderIfMatch) == "" && c.Header(HeaderIfUnmodifiedSince) == "" {
+			return false, &HTTPError{
+				Code:    StatusPreconditionRequired,
+				Message: "This request must be conditional; send If-Match or If-Unmodified-Since",
+			}
+		}
+	}
+	return c.CheckPreconditions(etag, lastModified)
+}
+
+// matchesResource evaluates an If-Match or If-None-Match header, where "*"
+// matches any existing representation.
+func matchesResource(header, etag string, exists, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return exists
+	}
+	return etagMatches(header, etag, strong)
+}
+
+// preconditionFailed returns the 412 error for the named failed condition.
+func preconditionFailed(header string) error {
+	return &HTTPError{
+		Code:    StatusPreconditionFailed,
+		Message: header + " precondition failed",
+	}
+}
+
+// notModified answers 304 Not Modified with the validators of the current
+// representation.
+func (c *Context) notModified(etag string, lastModified time.Time) error {
+	if etag != "" {
+		c.ctx.Response.Header.Set(HeaderETag, etag)
+	}
+	if !lastModified.IsZero() {
+		c.ctx.Response.Header.Set(HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	return c.NotModified()
+}
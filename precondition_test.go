@@ -0,0 +1,89 @@
+package kokoro
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 10, 0, 0, 500, time.UTC) // Sub-second part is dropped.
+	httpDate := "Wed, 01 May 2024 10:00:00 GMT"
+	before := "Tue, 30 Apr 2024 10:00:00 GMT"
+	tests := []struct {
+		name     string
+		method   string
+		require  bool
+		etag     string
+		modified time.Time
+		headers  [][2]string
+		status   int
+	}{
+		{"unconditional", MethodPut, false, `"v1"`, modified, nil, StatusOK},
+		{"if-match", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfMatch, `"v1"`}}, StatusOK},
+		{"if-match list", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfMatch, `"v0", "v1"`}}, StatusOK},
+		{"if-match stale", MethodPut, false, `"v2"`, modified, [][2]string{{HeaderIfMatch, `"v1"`}}, StatusPreconditionFailed},
+		{"if-match weak", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfMatch, `W/"v1"`}}, StatusPreconditionFailed},
+		{"if-match weak current", MethodPut, false, `W/"v1"`, modified, [][2]string{{HeaderIfMatch, `W/"v1"`}}, StatusPreconditionFailed},
+		{"if-match any", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfMatch, "*"}}, StatusOK},
+		{"if-match any missing", MethodPut, false, "", time.Time{}, [][2]string{{HeaderIfMatch, "*"}}, StatusPreconditionFailed},
+		{"unmodified since", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfUnmodifiedSince, httpDate}}, StatusOK},
+		{"modified since", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfUnmodifiedSince, before}}, StatusPreconditionFailed},
+		{"if-match over unmodified-since", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfMatch, `"v1"`}, {HeaderIfUnmodifiedSince, before}}, StatusOK},
+		{"unmodified-since invalid date", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfUnmodifiedSince, "yesterday"}}, StatusOK},
+		{"create if absent", MethodPut, false, "", time.Time{}, [][2]string{{HeaderIfNoneMatch, "*"}}, StatusOK},
+		{"create if absent exists", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfNoneMatch, "*"}}, StatusPreconditionFailed},
+		{"if-none-match put", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfNoneMatch, `W/"v1"`}}, StatusPreconditionFailed},
+		{"if-none-match get", MethodGet, false, `"v1"`, modified, [][2]string{{HeaderIfNoneMatch, `W/"v1"`}}, StatusNotModified},
+		{"if-none-match head", MethodHead, false, `"v1"`, modified, [][2]string{{HeaderIfNoneMatch, `"v1"`}}, StatusNotModified},
+		{"if-none-match changed", MethodGet, false, `"v2"`, modified, [][2]string{{HeaderIfNoneMatch, `"v1"`}}, StatusOK},
+		{"not modified since", MethodGet, false, `"v1"`, modified, [][2]string{{HeaderIfModifiedSince, httpDate}}, StatusNotModified},
+		{"modified since get", MethodGet, false, `"v1"`, modified, [][2]string{{HeaderIfModifiedSince, before}}, StatusOK},
+		{"if-modified-since put ignored", MethodPut, false, `"v1"`, modified, [][2]string{{HeaderIfModifiedSince, httpDate}}, StatusOK},
+		{"if-none-match over modified-since", MethodGet, false, `"v2"`, modified, [][2]string{{HeaderIfNoneMatch, `"v1"`}, {HeaderIfModifiedSince, httpDate}}, StatusOK},
+		{"required missing", MethodPut, true, `"v1"`, modified, nil, StatusPreconditionRequired},
+		{"required delete missing", MethodDelete, true, `"v1"`, modified, [][2]string{{HeaderIfNoneMatch, "*"}}, StatusPreconditionRequired},
+		{"required if-match", MethodPut, true, `"v1"`, modified, [][2]string{{HeaderIfMatch, `"v1"`}}, StatusOK},
+		{"required if-match stale", MethodPatch, true, `"v2"`, modified, [][2]string{{HeaderIfMatch, `"v1"`}}, StatusPreconditionFailed},
+		{"required unmodified since", MethodDelete, true, `"v1"`, modified, [][2]string{{HeaderIfUnmodifiedSince, httpDate}}, StatusOK},
+		{"required safe method", MethodGet, true, `"v1"`, modified, nil, StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Handle(tt.method, "/doc", func(c *Context) error {
+				check := c.CheckPreconditions
+				if tt.require {
+					check = c.RequirePreconditions
+				}
+				if done, err := check(tt.etag, tt.modified); done || err != nil {
+					return err
+				}
+				return c.SendText("updated")
+			})
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(tt.method)
+			ctx.Request.SetRequestURI("/doc")
+			for _, h := range tt.headers {
+				ctx.Request.Header.Set(h[0], h[1])
+			}
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if tt.status == StatusNotModified {
+				if got := string(ctx.Response.Header.Peek(HeaderETag)); got != tt.etag {
+					t.Errorf("304 ETag = %q, want %q", got, tt.etag)
+				}
+				if got := string(ctx.Response.Header.Peek(HeaderLastModified)); got != httpDate {
+					t.Errorf("304 Last-Modified = %q, want %q", got, httpDate)
+				}
+				if len(ctx.Response.Body()) != 0 {
+					t.Errorf("304 body = %q, want none", ctx.Response.Body())
+				}
+			}
+		})
+	}
+}
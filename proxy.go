@@ -0,0 +1,237 @@
+package kokoro
+
+import (
+	"log/slog"
+	"net/netip"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// defaultProxyHeaders are the headers read for the client IP when Server.ProxyHeaders is empty.
+var defaultProxyHeaders = []string{HeaderForwarded, HeaderForwardedFor, HeaderXRealIP}
+
+// proxyResolver is the compiled form of Server.TrustedProxies and Server.ProxyHeaders.
+type proxyResolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// peer describes the client of a request, as seen by the first trusted proxy.
+type peer struct {
+	ip     string // Client IP address.
+	scheme string // "http" or "https".
+	host   string // Requested host, possibly with a port.
+}
+
+// hop is one entry of a forwarding chain.
+type hop struct {
+	ip, proto, host string
+}
+
+// proxies returns the proxy resolver, compiling the server's proxy settings on first use.
+func (s *Server) proxies() *proxyResolver {
+	s.proxyOnce.Do(func() {
+		p := &proxyResolver{headers: s.ProxyHeaders}
+		if len(p.headers) == 0 {
+			p.headers = defaultProxyHeaders
+		}
+		for _, entry := range s.TrustedProxies {
+			prefix, err := parseTrustedProxy(entry)
+			if err != nil {
+				logger := s.Logger
+				if logger == nil {
+					logger = slog.Default()
+				}
+				logger.Warn("kokoro: ignoring invalid trusted proxy", "entry", entry, "error", err)
+				continue
+			}
+			p.trusted = append(p.trusted, prefix)
+		}
+		s.proxy = p
+	})
+	return s.proxy
+}
+
+// parseTrustedProxy parses a CIDR range or a single IP address.
+func parseTrustedProxy(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// trusts reports whether addr belongs to a trusted proxy.
+func (p *proxyResolver) trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustsString reports whether the textual IP address belongs to a trusted proxy.
+func (p *proxyResolver) trustsString(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && p.trusts(addr)
+}
+
+// resolve determines the client of the request. Forwarding headers are only
+// honored when the connection comes from a trusted proxy; chains are then walked
+// right to left, skipping trusted proxies, so entries forged by the client are ignored.
+func (p *proxyResolver) resolve(fctx *fasthttp.RequestCtx) peer {
+	res := peer{ip: fctx.RemoteIP().String(), scheme: "http", host: string(fctx.Host())}
+	if fctx.IsTLS() {
+		res.scheme = "https"
+	}
+	remote, ok := netip.AddrFromSlice(fctx.RemoteIP())
+	if !ok || !p.trusts(remote) {
+		return res
+	}
+
+	h := &fctx.Request.Header
+	var client hop
+	for _, name := range p.headers {
+		values := h.PeekAll(name)
+		if len(values) == 0 {
+			continue
+		}
+		switch {
+		case strings.EqualFold(name, HeaderForwarded):
+			client = p.pick(parseForwarded(values))
+		case strings.EqualFold(name, HeaderForwardedFor):
+			client = p.pick(parseForwardedFor(values))
+		default:
+			// Single-address headers such as X-Real-IP or CF-Connecting-IP.
+			client = hop{ip: strings.TrimSpace(string(values[len(values)-1]))}
+		}
+		if _, err := netip.ParseAddr(client.ip); err == nil {
+			break
+		}
+		client = hop{}
+	}
+
+	if client.ip != "" {
+		res.ip = client.ip
+	}
+	// X-Forwarded-Proto and X-Forwarded-Host are taken from their last value, the
+	// one set by the nearest proxy.
+	if client.proto == "" {
+		client.proto = lastValue(h.PeekAll(HeaderForwardedProto))
+	}
+	if client.host == "" {
+		client.host = lastValue(h.PeekAll(HeaderForwardedHost))
+	}
+	if proto := strings.ToLower(client.proto); proto == "http" || proto == "https" {
+		res.scheme = proto
+	}
+	if validForwardedHost(client.host) {
+		res.host = client.host
+	}
+	return res
+}
+
+// pick returns the rightmost hop not made by a trusted proxy, or the leftmost
+// hop when every hop is trusted.
+func (p *proxyResolver) pick(hops []hop) hop {
+	for i := len(hops) - 1; i >= 0; i-- {
+		if i == 0 || !p.trustsString(hops[i].ip) {
+			return hops[i]
+		}
+	}
+	return hop{}
+}
+
+// parseForwardedFor parses X-Forwarded-For header values into hops.
+func parseForwardedFor(values [][]byte) []hop {
+	var hops []hop
+	for _, v := range values {
+		for _, ip := range strings.Split(string(v), ",") {
+			hops = append(hops, hop{ip: strings.TrimSpace(ip)})
+		}
+	}
+	return hops
+}
+
+// parseForwarded parses Forwarded header values, as defined by RFC 7239, into hops.
+func parseForwarded(values [][]byte) []hop {
+	var hops []hop
+	for _, v := range values {
+		for _, element := range strings.Split(string(v), ",") {
+			var entry hop
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				value = strings.Trim(value, `"`)
+				switch strings.ToLower(key) {
+				case "for":
+					entry.ip = forwardedNode(value)
+				case "proto":
+					entry.proto = value
+				case "host":
+					entry.host = value
+				}
+			}
+			hops = append(hops, entry)
+		}
+	}
+	return hops
+}
+
+// forwardedNode strips the port from a Forwarded node, e.g. "[2001:db8::1]:4711"
+// or "192.0.2.60:8080". Obfuscated identifiers such as "unknown" are returned as-is.
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexByte(node, ']'); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if strings.Count(node, ":") == 1 {
+		node, _, _ = strings.Cut(node, ":")
+	}
+	return node
+}
+
+// lastValue returns the trimmed last comma-separated element of the header values.
+func lastValue(values [][]byte) string {
+	if len(values) == 0 {
+		return ""
+	}
+	v := string(values[len(values)-1])
+	if i := strings.LastIndexByte(v, ','); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// validForwardedHost reports whether host is usable as a request host.
+func validForwardedHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, " \t/\\@?#")
+}
+
+// peer returns the client of the request, resolved once per request.
+func (c *Context) peer() *peer {
+	if !c.cache.resolved {
+		c.cache.peer = c.server.proxies().resolve(c.ctx)
+		c.cache.resolved = true
+	}
+	return &c.cache.peer
+}
@@ -0,0 +1,70 @@
+package kokoro
+
+import (
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestProxyResolution(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.1", "::ffff:172.16.0.0/108"}
+	tests := []struct {
+		name    string
+		remote  string
+		headers [][2]string
+		proxy   []string // Server.ProxyHeaders.
+		ip      string
+		baseURL string
+	}{
+		{"direct", "203.0.113.9", nil, nil, "203.0.113.9", "http://example.com"},
+		{"untrusted peer", "203.0.113.9", [][2]string{{HeaderForwardedFor, "1.1.1.1"}, {HeaderForwardedProto, "https"}, {HeaderForwardedHost, "evil.io"}}, nil, "203.0.113.9", "http://example.com"},
+		{"x-forwarded-for", "10.0.0.1", [][2]string{{HeaderForwardedFor, "198.51.100.7"}}, nil, "198.51.100.7", "http://example.com"},
+		{"forged prefix", "10.0.0.1", [][2]string{{HeaderForwardedFor, "1.1.1.1, 198.51.100.7"}}, nil, "198.51.100.7", "http://example.com"},
+		{"trusted hops skipped", "10.0.0.1", [][2]string{{HeaderForwardedFor, "198.51.100.7, 10.1.1.1, 192.168.1.1"}}, nil, "198.51.100.7", "http://example.com"},
+		{"all hops trusted", "10.0.0.1", [][2]string{{HeaderForwardedFor, "10.2.2.2, 10.1.1.1"}}, nil, "10.2.2.2", "http://example.com"},
+		{"repeated headers", "10.0.0.1", [][2]string{{HeaderForwardedFor, "1.1.1.1"}, {HeaderForwardedFor, "198.51.100.7"}}, nil, "198.51.100.7", "http://example.com"},
+		{"single trusted ip", "192.168.1.1", [][2]string{{HeaderForwardedFor, "198.51.100.7"}}, nil, "198.51.100.7", "http://example.com"},
+		{"ipv4-mapped range", "172.16.5.5", [][2]string{{HeaderForwardedFor, "198.51.100.7"}}, nil, "198.51.100.7", "http://example.com"},
+		{"invalid address", "10.0.0.1", [][2]string{{HeaderForwardedFor, "not-an-ip"}}, nil, "10.0.0.1", "http://example.com"},
+		{"proto and host", "10.0.0.1", [][2]string{{HeaderForwardedFor, "198.51.100.7"}, {HeaderForwardedProto, "http, HTTPS"}, {HeaderForwardedHost, "api.example.com:8443"}}, nil, "198.51.100.7", "https://api.example.com:8443"},
+		{"invalid proto", "10.0.0.1", [][2]string{{HeaderForwardedProto, "javascript"}}, nil, "10.0.0.1", "http://example.com"},
+		{"invalid host", "10.0.0.1", [][2]string{{HeaderForwardedHost, "evil.io/path"}}, nil, "10.0.0.1", "http://example.com"},
+		{"forwarded", "10.0.0.1", [][2]string{{HeaderForwarded, `for=198.51.100.7;proto=https;host=app.example.com`}}, nil, "198.51.100.7", "https://app.example.com"},
+		{"forwarded chain", "10.0.0.1", [][2]string{{HeaderForwarded, `for=1.1.1.1;host=evil.io, for="[2001:db8::1]:4711";proto=https, for=10.1.1.1`}}, nil, "2001:db8::1", "https://example.com"},
+		{"forwarded port", "10.0.0.1", [][2]string{{HeaderForwarded, `for="192.0.2.60:8080"`}}, nil, "192.0.2.60", "http://example.com"},
+		{"forwarded obfuscated", "10.0.0.1", [][2]string{{HeaderForwarded, `for=unknown`}, {HeaderForwardedFor, "198.51.100.7"}}, nil, "198.51.100.7", "http://example.com"},
+		{"forwarded first", "10.0.0.1", [][2]string{{HeaderForwarded, `for=198.51.100.7`}, {HeaderForwardedFor, "198.51.100.8"}}, nil, "198.51.100.7", "http://example.com"},
+		{"x-real-ip", "10.0.0.1", [][2]string{{HeaderXRealIP, " 198.51.100.7 "}}, nil, "198.51.100.7", "http://example.com"},
+		{"custom header", "10.0.0.1", [][2]string{{"CF-Connecting-IP", "198.51.100.7"}, {HeaderForwardedFor, "198.51.100.8"}}, []string{"CF-Connecting-IP"}, "198.51.100.7", "http://example.com"},
+		{"custom header only", "10.0.0.1", [][2]string{{HeaderForwardedFor, "198.51.100.8"}}, []string{"CF-Connecting-IP"}, "10.0.0.1", "http://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.TrustedProxies = trusted
+			s.ProxyHeaders = tt.proxy
+			var ip, baseURL string
+			s.GET("/", func(c *Context) error {
+				ip, baseURL = c.RealIP(), c.BaseURL()
+				return nil
+			})
+
+			var ctx fasthttp.RequestCtx
+			ctx.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.ParseIP(tt.remote), Port: 1234}, nil)
+			ctx.Request.Header.SetHost("example.com")
+			ctx.Request.SetRequestURI("/")
+			for _, h := range tt.headers {
+				ctx.Request.Header.Add(h[0], h[1])
+			}
+			s.Handler(&ctx)
+
+			if ip != tt.ip {
+				t.Errorf("RealIP = %q, want %q", ip, tt.ip)
+			}
+			if baseURL != tt.baseURL {
+				t.Errorf("BaseURL = %q, want %q", baseURL, tt.baseURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package kokoro
+
+import (
+	"fmt"
+	"time"
+)
+
+// queryValue parses the query parameter named key into a value of type T.
+// If the key is absent or empty, the optional default (or the zero value) is returned
+// with a nil error. A malformed value results in a 400 *HTTPError.
+func queryValue[T any](c *Context, key string, defaultValue []T) (T, error) {
+	var v T
+	raw := c.ctx.QueryArgs().Peek(key)
+	if len(raw) == 0 {
+		if len(defaultValue) > 0 {
+			return defaultValue[0], nil
+		}
+		return v, nil
+	}
+	if err := parseParam(&v, string(raw)); err != nil {
+		return v, &HTTPError{Code: StatusBadRequest, Message: fmt.Sprintf("invalid query parameter %q", key)}
+	}
+	return v, nil
+}
+
+// QueryInt retrieves a query parameter by its key and parses it as an int.
+// An optional defaultValue is returned if the key is not found.
+// Returns a 400 *HTTPError if the value is not a valid integer.
+func (c *Context) QueryInt(key string, defaultValue ...int) (int, error) {
+	return queryValue(c, key, defaultValue)
+}
+
+// QueryBool retrieves a query parameter by its key and parses it as a bool
+// (accepting the values understood by strconv.ParseBool, e.g. "1", "true", "false").
+// An optional defaultValue is returned if the key is not found.
+// Returns a 400 *HTTPError if the value is not a valid boolean.
+func (c *Context) QueryBool(key string, defaultValue ...bool) (bool, error) {
+	return queryValue(c, key, defaultValue)
+}
+
+// QueryFloat retrieves a query parameter by its key and parses it as a float64.
+// An optional defaultValue is returned if the key is not found.
+// Returns a 400 *HTTPError if the value is not a valid number.
+func (c *Context) QueryFloat(key string, defaultValue ...float64) (float64, error) {
+	return queryValue(c, key, defaultValue)
+}
+
+// QueryDuration retrieves a query parameter by its key and parses it with
+// time.ParseDuration (e.g., "1h30m").
+// An optional defaultValue is returned if the key is not found.
+// Returns a 400 *HTTPError if the value is not a valid duration.
+func (c *Context) QueryDuration(key string, defaultValue ...time.Duration) (time.Duration, error) {
+	return queryValue(c, key, defaultValue)
+}
+
+// QueryTime retrieves a query parameter by its key and parses it with the given
+// time layout (e.g., time.RFC3339 or time.DateOnly).
+// An optional defaultValue is returned if the key is not found.
+// Returns a 400 *HTTPError if the value does not match the layout.
+func (c *Context) QueryTime(key, layout string, defaultValue ...time.Time) (time.Time, error) {
+	raw := c.ctx.QueryArgs().Peek(key)
+	if len(raw) == 0 {
+		if len(defaultValue) > 0 {
+			return defaultValue[0], nil
+		}
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(layout, string(raw))
+	if err != nil {
+		return time.Time{}, &HTTPError{Code: StatusBadRequest, Message: fmt.Sprintf("invalid query parameter %q", key)}
+	}
+	return t, nil
+}
@@ -0,0 +1,91 @@
+package kokoro
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestQueryAccessors(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		query  string
+		get    func(c *Context) (any, error)
+		status int
+		want   string
+	}{
+		{"int", "n=42", func(c *Context) (any, error) { return c.QueryInt("n") }, StatusOK, "42"},
+		{"int missing", "", func(c *Context) (any, error) { return c.QueryInt("n") }, StatusOK, "0"},
+		{"int default", "", func(c *Context) (any, error) { return c.QueryInt("n", 10) }, StatusOK, "10"},
+		{"int empty uses default", "n=", func(c *Context) (any, error) { return c.QueryInt("n", 10) }, StatusOK, "10"},
+		{"int invalid", "n=ten", func(c *Context) (any, error) { return c.QueryInt("n", 10) }, StatusBadRequest, ""},
+		{"bool", "on=1", func(c *Context) (any, error) { return c.QueryBool("on") }, StatusOK, "true"},
+		{"bool invalid", "on=yes", func(c *Context) (any, error) { return c.QueryBool("on") }, StatusBadRequest, ""},
+		{"float", "f=-1.5", func(c *Context) (any, error) { return c.QueryFloat("f") }, StatusOK, "-1.5"},
+		{"float invalid", "f=1,5", func(c *Context) (any, error) { return c.QueryFloat("f") }, StatusBadRequest, ""},
+		{"duration", "d=1h30m", func(c *Context) (any, error) { return c.QueryDuration("d") }, StatusOK, "1h30m0s"},
+		{"duration invalid", "d=90", func(c *Context) (any, error) { return c.QueryDuration("d") }, StatusBadRequest, ""},
+		{"time", "t=2024-05-01", func(c *Context) (any, error) { return c.QueryTime("t", time.DateOnly) }, StatusOK, day.String()},
+		{"time default", "", func(c *Context) (any, error) { return c.QueryTime("t", time.DateOnly, day) }, StatusOK, day.String()},
+		{"time invalid", "t=01/05/2024", func(c *Context) (any, error) { return c.QueryTime("t", time.DateOnly) }, StatusBadRequest, ""},
+		{"repeated reads first", "n=1&n=2", func(c *Context) (any, error) { return c.QueryInt("n") }, StatusOK, "1"},
+		{"params keep last", "n=1&n=2", func(c *Context) (any, error) { return c.QueryParams()["n"], nil }, StatusOK, "2"},
+		{"all", "tag=a&x=1&tag=b", func(c *Context) (any, error) { return c.QueryAll("tag"), nil }, StatusOK, "[a b]"},
+		{"all missing", "x=1", func(c *Context) (any, error) { return c.QueryAll("tag") == nil, nil }, StatusOK, "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.GET("/", func(c *Context) error {
+				v, err := tt.get(c)
+				if err != nil {
+					return err
+				}
+				return c.SendText(fmt.Sprint(v))
+			})
+			var ctx fasthttp.RequestCtx
+			ctx.Request.SetRequestURI("/?" + tt.query)
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if tt.status == StatusOK {
+				if got := string(ctx.Response.Body()); got != tt.want {
+					t.Errorf("value = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers [][2]string
+		key     string
+		want    []string
+	}{
+		{"single", [][2]string{{"X-Tag", "a"}}, "X-Tag", []string{"a"}},
+		{"repeated", [][2]string{{"X-Tag", "a"}, {"X-Other", "z"}, {"X-Tag", "b"}}, "X-Tag", []string{"a", "b"}},
+		{"case-insensitive", [][2]string{{"x-tag", "a"}}, "X-TAG", []string{"a"}},
+		{"missing", [][2]string{{"X-Other", "z"}}, "X-Tag", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			for _, h := range tt.headers {
+				ctx.Request.Header.Add(h[0], h[1])
+			}
+			c := &Context{ctx: &ctx}
+
+			if got := c.HeaderValues(tt.key); !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("HeaderValues(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package kokoro
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Errors returned by Context.Ranges.
+var (
+	// ErrNoRange is returned when the request has no Range header.
+	ErrNoRange = errors.New("no Range header")
+
+	// ErrRangeNotSatisfiable is returned when none of the requested ranges overlaps
+	// the content, which calls for a 416 Range Not Satisfiable response.
+	ErrRangeNotSatisfiable = errors.New("no satisfiable byte range")
+)
+
+// maxRanges is the number of ranges above which the Range header is ignored and the
+// full content is sent, to avoid the cost of responses made of many tiny parts.
+const maxRanges = 32
+
+// SendRange sends content, of the given size and modification time, honoring the
+// request's Range header so clients can seek in media or resume downloads.
+//
+// Requests without a satisfiable Range get the full content with 200. A single range
+// is sent as 206 Partial Content with a Content-Range header, several ranges as a
+// multipart/byteranges body. When no range overlaps the content, a 416 *HTTPError is
+// returned and "Content-Range: bytes */size" is set. Malformed Range headers, and
+// ranges whose If-Range validator does not match the ETag header or modtime, are
+// ignored, as RFC 9110, Section 14.2 requires.
+//
+// The ETag, Content-Type and caching headers should be set before calling it; the
+// Content-Type is sniffed from the content when missing. A zero modtime omits the
+// Last-Modified header. If content implements io.Closer, it is closed once sent.
+//
+// Example:
+//
+//	f, err := os.Open(path)
+//	if err != nil {
+//	    return err
+//	}
+//	info, _ := f.Stat()
+//	c.ContentType("video/mp4")
+//	return c.SendRange(f, info.Size(), info.ModTime())
+func (c *Context) SendRange(content io.ReadSeeker, size int64, modtime time.Time) error {
+	h := &c.ctx.Response.Header
+	h.Set(HeaderAcceptRanges, "bytes")
+	if modtime = modtime.Truncate(time.Second); !modtime.IsZero() && len(h.Peek(HeaderLastModified)) == 0 {
+		h.Set(HeaderLastModified, modtime.UTC().Format(http.TimeFormat))
+	}
+	contentType, err := responseContentType(h, content)
+	if err != nil {
+		closeContent(content)
+		return err
+	}
+
+	var ranges []HTTPRange
+	if method := c.Method(); (method == MethodGet || method == MethodHead) && c.ifRangeMatches(modtime) {
+		r, err := c.Ranges(size)
+		switch {
+		case errors.Is(err, ErrRangeNotSatisfiable):
+			closeContent(content)
+			h.Set(HeaderContentRange, "bytes */"+strconv.FormatInt(size, 10))
+			return &HTTPError{Code: StatusRangeNotSatisfiable, Message: "Range Not Satisfiable"}
+		case err == nil && len(r.Ranges) <= maxRanges:
+			ranges = r.Ranges
+		}
+	}
+
+	switch len(ranges) {
+	case 0:
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			closeContent(content)
+			return err
+		}
+		c.ctx.SetStatusCode(StatusOK)
+		c.ctx.SetBodyStream(content, int(size))
+	case 1:
+		rg := ranges[0]
+		h.Set(HeaderContentRange, contentRange(rg, size))
+		c.ctx.SetStatusCode(StatusPartialContent)
+		c.ctx.SetBodyStream(&rangeReader{content: content, start: rg.Start, n: rg.End - rg.Start + 1}, int(rg.End-rg.Start+1))
+	default:
+		boundary := newBoundary()
+		readers := make([]io.Reader, 0, 2*len(ranges)+1)
+		var length int64
+		for i, rg := range ranges {
+			part := "\r\n--" + boundary + "\r\n" +
+				HeaderContentType + ": " + contentType + "\r\n" +
+				HeaderContentRange + ": " + contentRange(rg, size) + "\r\n\r\n"
+			if i == 0 {
+				part = part[2:] // No CRLF before the first boundary.
+			}
+			n := rg.End - rg.Start + 1
+			readers = append(readers, strings.NewReader(part), &rangeReader{content: content, start: rg.Start, n: n})
+			length += int64(len(part)) + n
+		}
+		end := "\r\n--" + boundary + "--\r\n"
+		readers = append(readers, strings.NewReader(end))
+		length += int64(len(end))
+
+		c.ContentType("multipart/byteranges; boundary=" + boundary)
+		c.ctx.SetStatusCode(StatusPartialContent)
+		c.ctx.SetBodyStream(&multiReadCloser{Reader: io.MultiReader(readers...), content: content}, int(length))
+	}
+	return nil
+}
+
+// ifRangeMatches reports whether the request's If-Range validator, if any, matches
+// the ETag header or the modification time (RFC 9110, Section 13.1.5).
+func (c *Context) ifRangeMatches(modtime time.Time) bool {
+	ifRange := strings.TrimSpace(c.Header(HeaderIfRange))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return etagMatches(ifRange, string(c.ctx.Response.Header.Peek(HeaderETag)), true)
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && !modtime.IsZero() && modtime.Equal(t)
+}
+
+// responseContentType returns the Content-Type set on the response, sniffing and
+// setting it from the first bytes of content when the handler did not set one.
+func responseContentType(h *fasthttp.ResponseHeader, content io.ReadSeeker) (string, error) {
+	contentType := explicitContentType(h)
+	if contentType != "" {
+		return contentType, nil
+	}
+
+	var buf [512]byte
+	n, err := io.ReadFull(content, buf[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	contentType = http.DetectContentType(buf[:n])
+	h.SetContentType(contentType)
+	return contentType, nil
+}
+
+// explicitContentType returns the Content-Type set on the response, or "" when
+// only fasthttp's default would be sent.
+func explicitContentType(h *fasthttp.ResponseHeader) string {
+	h.SetNoDefaultContentType(true)
+	contentType := string(h.ContentType())
+	h.SetNoDefaultContentType(false)
+	return contentType
+}
+
+// contentRange formats the Content-Range value of rg within content of the given size.
+func contentRange(rg HTTPRange, size int64) string {
+	return "bytes " + strconv.FormatInt(rg.Start, 10) + "-" + strconv.FormatInt(rg.End, 10) + "/" + strconv.FormatInt(size, 10)
+}
+
+// newBoundary returns a random multipart boundary.
+func newBoundary() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// closeContent closes content if it implements io.Closer.
+func closeContent(content io.Reader) {
+	if cl, ok := content.(io.Closer); ok {
+		_ = cl.Close()
+	}
+}
+
+// rangeReader reads n bytes of content from start, seeking on the first read so
+// several rangeReaders can share content when read one after another.
+type rangeReader struct {
+	content io.ReadSeeker
+	start   int64
+	n       int64
+	seeked  bool
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if !r.seeked {
+		if _, err := r.content.Seek(r.start, io.SeekStart); err != nil {
+			return 0, err
+		}
+		r.seeked = true
+	}
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.content.Read(p)
+	r.n -= int64(n)
+	if err == io.EOF && r.n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Close closes content; fasthttp calls it once a single-range body has been written.
+func (r *rangeReader) Close() error {
+	closeContent(r.content)
+	return nil
+}
+
+// multiReadCloser closes content once the multipart body has been written.
+type multiReadCloser struct {
+	io.Reader
+	content io.Reader
+}
+
+func (m *multiReadCloser) Close() error {
+	closeContent(m.content)
+	return nil
+}
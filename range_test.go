@@ -0,0 +1,128 @@
+package kokoro
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// closeTracker is a ReadSeeker recording whether it was closed.
+type closeTracker struct {
+	*strings.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSendRange(t *testing.T) {
+	const content = "0123456789abcdefghij"
+	modtime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	httpDate := "Wed, 01 May 2024 10:00:00 GMT"
+	many := "bytes=" + strings.Repeat("0-0,", maxRanges) + "1-1"
+
+	tests := []struct {
+		name         string
+		method       string
+		headers      [][2]string
+		status       int
+		body         string // For multipart responses, the parts as "range:data" lines.
+		contentRange string // Expected Content-Range.
+	}{
+		{"full", MethodGet, nil, StatusOK, content, ""},
+		{"head", MethodHead, [][2]string{{HeaderRange, "bytes=0-4"}}, StatusPartialContent, "", "bytes 0-4/20"},
+		{"first bytes", MethodGet, [][2]string{{HeaderRange, "bytes=0-4"}}, StatusPartialContent, "01234", "bytes 0-4/20"},
+		{"suffix", MethodGet, [][2]string{{HeaderRange, "bytes=-5"}}, StatusPartialContent, "fghij", "bytes 15-19/20"},
+		{"open end", MethodGet, [][2]string{{HeaderRange, "bytes=15-"}}, StatusPartialContent, "fghij", "bytes 15-19/20"},
+		{"clamped", MethodGet, [][2]string{{HeaderRange, "bytes=18-100"}}, StatusPartialContent, "ij", "bytes 18-19/20"},
+		{"multiple", MethodGet, [][2]string{{HeaderRange, "bytes=0-1, 10-12"}}, StatusPartialContent, "bytes 0-1/20:01\nbytes 10-12/20:abc\n", ""},
+		{"not satisfiable", MethodGet, [][2]string{{HeaderRange, "bytes=30-40"}}, StatusRangeNotSatisfiable, "", "bytes */20"},
+		{"malformed", MethodGet, [][2]string{{HeaderRange, "bytes=abc"}}, StatusOK, content, ""},
+		{"other unit", MethodGet, [][2]string{{HeaderRange, "items=0-1"}}, StatusOK, content, ""},
+		{"too many ranges", MethodGet, [][2]string{{HeaderRange, many}}, StatusOK, content, ""},
+		{"unsafe method", MethodPost, [][2]string{{HeaderRange, "bytes=0-4"}}, StatusOK, content, ""},
+		{"if-range etag", MethodGet, [][2]string{{HeaderRange, "bytes=0-4"}, {HeaderIfRange, `"v1"`}}, StatusPartialContent, "01234", "bytes 0-4/20"},
+		{"if-range stale etag", MethodGet, [][2]string{{HeaderRange, "bytes=0-4"}, {HeaderIfRange, `"v0"`}}, StatusOK, content, ""},
+		{"if-range weak etag", MethodGet, [][2]string{{HeaderRange, "bytes=0-4"}, {HeaderIfRange, `W/"v1"`}}, StatusOK, content, ""},
+		{"if-range date", MethodGet, [][2]string{{HeaderRange, "bytes=0-4"}, {HeaderIfRange, httpDate}}, StatusPartialContent, "01234", "bytes 0-4/20"},
+		{"if-range stale date", MethodGet, [][2]string{{HeaderRange, "bytes=0-4"}, {HeaderIfRange, "Tue, 30 Apr 2024 10:00:00 GMT"}}, StatusOK, content, ""},
+		{"if-range stale not satisfiable", MethodGet, [][2]string{{HeaderRange, "bytes=30-40"}, {HeaderIfRange, `"v0"`}}, StatusOK, content, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &closeTracker{Reader: strings.NewReader(content)}
+			s := New()
+			s.Handle(tt.method, "/", func(c *Context) error {
+				c.SetHeader(HeaderETag, `"v1"`)
+				return c.SendRange(r, int64(len(content)), modtime)
+			})
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(tt.method)
+			ctx.Request.SetRequestURI("/")
+			for _, h := range tt.headers {
+				ctx.Request.Header.Set(h[0], h[1])
+			}
+			s.Handler(&ctx)
+
+			resp := &ctx.Response
+			if got := resp.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if got := string(resp.Header.Peek(HeaderContentRange)); got != tt.contentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.contentRange)
+			}
+			if got := string(resp.Header.Peek(HeaderAcceptRanges)); got != "bytes" {
+				t.Errorf("Accept-Ranges = %q, want bytes", got)
+			}
+			if got := string(resp.Header.Peek(HeaderLastModified)); got != httpDate {
+				t.Errorf("Last-Modified = %q, want %q", got, httpDate)
+			}
+
+			body := string(resp.Body())
+			if mediaType, params, _ := mime.ParseMediaType(string(resp.Header.ContentType())); mediaType == "multipart/byteranges" {
+				body = readByteranges(t, body, params["boundary"])
+				if got := resp.Header.ContentLength(); got != len(resp.Body()) {
+					t.Errorf("Content-Length = %d, want %d", got, len(resp.Body()))
+				}
+			} else if tt.status != StatusRangeNotSatisfiable {
+				if got := string(resp.Header.ContentType()); got != "text/plain; charset=utf-8" {
+					t.Errorf("Content-Type = %q, want the sniffed type", got)
+				}
+			}
+			if tt.method != MethodHead && tt.status != StatusRangeNotSatisfiable && body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+			if tt.method != MethodHead && !r.closed {
+				t.Error("content not closed")
+			}
+		})
+	}
+}
+
+// readByteranges returns the parts of a multipart/byteranges body as "range:data" lines.
+func readByteranges(t *testing.T, body, boundary string) string {
+	t.Helper()
+	var b strings.Builder
+	mr := multipart.NewReader(strings.NewReader(body), boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return b.String()
+		} else if err != nil {
+			t.Fatalf("malformed multipart/byteranges body %q: %v", body, err)
+		}
+		if got := p.Header.Get(HeaderContentType); got != "text/plain; charset=utf-8" {
+			t.Errorf("part Content-Type = %q", got)
+		}
+		data, _ := io.ReadAll(p)
+		fmt.Fprintf(&b, "%s:%s\n", p.Header.Get(HeaderContentRange), data)
+	}
+}
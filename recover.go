@@ -0,0 +1,93 @@
+package kokoro
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+// PanicError describes a panic recovered while handling a request.
+type PanicError struct {
+	Value any    // The value passed to panic.
+	Stack []byte // The stack trace of the panicking goroutine.
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// WithRecovery enables or disables the recovery of panics raised by handlers and
+// middlewares. Recovery is enabled by default; a recovered panic is reported to the
+// hook registered with OnPanic and answered with a 500 through the error handlers.
+func (s *Server) WithRecovery(value bool) *Server {
+	s.recoverPanics = value
+	return s
+}
+
+// OnPanic registers a hook invoked with every recovered panic before the error
+// response is rendered, e.g. to forward it to a crash reporting service.
+// Without a hook, panics are logged with the request's Context.Logger.
+//
+// Example:
+//
+//	s.OnPanic(func(c *kokoro.Context, p *kokoro.PanicError) {
+//	    sentry.CaptureException(p)
+//	})
+func (s *Server) OnPanic(hook func(c *Context, p *PanicError)) *Server {
+	s.panicHook = hook
+	return s
+}
+
+// recoverPanic must be deferred directly. It converts a panic into a 500 HTTPError
+// carrying the *PanicError as its cause and the stack as an internal detail, and
+// routes it through the error handlers of the matched route.
+func (s *Server) recoverPanic(c *Context) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	p := &PanicError{Value: v, Stack: debug.Stack()}
+
+	defer func() {
+		// The hook or an error handler panicked as well; give up on shaping the response.
+		if recover() != nil {
+			c.resetResponse()
+			c.ctx.SetStatusCode(StatusInternalServerError)
+		}
+	}()
+
+	if s.panicHook != nil {
+		s.panicHook(c, p)
+	} else {
+		c.Logger().Error("kokoro: panic recovered",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Any("panic", v),
+			slog.String("stack", string(p.Stack)),
+		)
+	}
+
+	// Discard whatever the handler wrote before panicking.
+	c.resetResponse()
+	err := NewHTTPError(StatusInternalServerError).WithCause(p)
+	err.Internal = map[string]any{"stack": string(p.Stack)}
+	c.HandleError(err)
+}
+
+// resetResponse clears the status, headers, cookies and body of the response. Only
+// the headers registered with PropagateHeader and KeepHeader, such as the request
+// ID, survive.
+func (c *Context) resetResponse() {
+	c.ctx.Response.Reset()
+	for _, h := range c.propagated {
+		c.ctx.Response.Header.Set(h.key, h.value)
+	}
+}
@@ -0,0 +1,89 @@
+package kokoro
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	var recovered *PanicError
+	s := New()
+	s.OnPanic(func(c *Context, p *PanicError) { recovered = p })
+	s.GET("/", func(c *Context) error {
+		c.SetHeader("X-Request-ID", "abc")
+		c.PropagateHeader("X-Request-ID", "abc")
+		c.SetHeader("Access-Control-Allow-Origin", "https://app.example.com")
+		c.KeepHeader("Access-Control-Allow-Origin", "https://app.example.com")
+		c.SetHeader("X-Partial", "1")
+		c.ctx.Response.Header.Set(HeaderSetCookie, "session=leaked")
+		c.Status(StatusAccepted)
+		_ = c.SendText("half written")
+		panic(errors.New("boom"))
+	})
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/")
+	s.Handler(&ctx)
+
+	if recovered == nil || recovered.Error() != "panic: boom" || len(recovered.Stack) == 0 {
+		t.Fatalf("recovered = %v, want the panic with its stack", recovered)
+	}
+	if got := ctx.Response.StatusCode(); got != StatusInternalServerError {
+		t.Errorf("status = %d, want 500", got)
+	}
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"X-Request-ID", "abc"},
+		{"Access-Control-Allow-Origin", "https://app.example.com"},
+		{"X-Partial", ""},
+		{HeaderSetCookie, ""},
+	}
+	for _, tt := range tests {
+		if got := string(ctx.Response.Header.Peek(tt.header)); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	if got := string(ctx.Response.Body()); got == "half written" {
+		t.Errorf("body = %q, want the error response", got)
+	}
+}
+
+func TestDoCopiesOutboundHeaders(t *testing.T) {
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	var received fasthttp.RequestHeader
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) { ctx.Request.Header.CopyTo(&received) })
+
+	s := New()
+	s.Client = &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+	s.GET("/", func(c *Context) error {
+		c.PropagateHeader("X-Request-ID", "abc")
+		c.PropagateHeader("X-Request-ID", "def")
+		c.KeepHeader("Access-Control-Allow-Origin", "*")
+		req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		req.SetRequestURI("http://backend/")
+		return c.Do(req, resp)
+	})
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/")
+	s.Handler(&ctx)
+
+	if got := ctx.Response.StatusCode(); got != StatusOK {
+		t.Fatalf("status = %d, want 200", got)
+	}
+	if got := string(received.Peek("X-Request-ID")); got != "def" {
+		t.Errorf("outbound X-Request-ID = %q, want %q", got, "def")
+	}
+	if got := received.Peek("Access-Control-Allow-Origin"); got != nil {
+		t.Errorf("outbound Access-Control-Allow-Origin = %q, want it unset", got)
+	}
+}
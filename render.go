@@ -0,0 +1,237 @@
+package kokoro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Renderer renders named templates for Context.Render.
+type Renderer interface {
+	// Render writes the template called name, executed with data, to w. c is the
+	// context of the request being answered, for request-scoped template functions.
+	Render(w io.Writer, name string, data any, c *Context) error
+}
+
+// Render renders the template called name with data using the Server's Renderer,
+// and sends it with the status code set so far, 200 by default. The Content-Type
+// is set to "text/html; charset=utf-8" unless one was set beforehand. Nothing is
+// written when rendering fails, so the error can still be rendered by the error handler.
+//
+// Example:
+//
+//	s.GET("/users/{id}", func(c *kokoro.Context) error {
+//	    return c.Render("users/show", user)
+//	})
+func (c *Context) Render(name string, data any) error {
+	if c.server.Renderer == nil {
+		return errors.New("kokoro: Render called without a Server Renderer")
+	}
+	var buf bytes.Buffer
+	if err := c.server.Renderer.Render(&buf, name, data, c); err != nil {
+		return err
+	}
+	if explicitContentType(&c.ctx.Response.Header) == "" {
+		c.ContentType("text/html; charset=utf-8")
+	}
+	c.ctx.SetBody(buf.Bytes())
+	return nil
+}
+
+// HTMLConfig configures an HTMLRenderer.
+type HTMLConfig struct {
+	// Extension is the file extension of the templates. Defaults to ".html".
+	Extension string
+
+	// Layout is the name of the layout wrapping every page, e.g. "layouts/main".
+	// It renders the page with {{ template "content" . }}. Pages are rendered on
+	// their own when empty.
+	Layout string
+
+	// Funcs are additional functions available to the templates. They take
+	// precedence over the built-in url and csrf functions.
+	Funcs template.FuncMap
+
+	// Reload parses the templates again on every render, so changes show up
+	// without restarting the server. Meant for development only.
+	Reload bool
+}
+
+// HTMLRenderer is a Renderer based on html/template, which escapes data
+// according to its context in the page.
+//
+// Templates are loaded from a file system, and named after their path without
+// the extension, e.g. "users/show" for "users/show.html". The templates under
+// "layouts/" and "partials/" are shared with every page: partials are included
+// with {{ template "partials/nav" . }}, and can be rendered on their own, e.g. to
+// answer htmx requests. Pages can define blocks declared by their layout, such as
+// {{ define "title" }}.
+//
+// Besides the configured Funcs, templates can use:
+//
+//   - url: builds a path from the pattern of a registered route with ReversePath,
+//     e.g. {{ url "/users/{id:int}" "id" .ID }}. Rendering fails when no route of
+//     the Server was registered with the pattern, so links cannot silently go stale.
+//   - csrf: returns the CSRF token of the request, e.g.
+//     <input type="hidden" name="_csrf" value="{{ csrf }}">.
+type HTMLRenderer struct {
+	fsys  fs.FS
+	cfg   HTMLConfig
+	pages map[string]*htmlPage
+}
+
+// htmlPage is a template set ready to render one page.
+type htmlPage struct {
+	tmpl *template.Template // Never executed, so it can be cloned.
+	root string             // Template to execute: the layout, or the page itself.
+	pool sync.Pool          // Clones of tmpl, each used by one render at a time.
+}
+
+// NewHTMLRenderer creates an HTMLRenderer for the templates of fsys, which are
+// parsed immediately so errors surface at startup. It works with any file system,
+// including embed.FS:
+//
+//	//go:embed views
+//	var views embed.FS
+//
+//	sub, _ := fs.Sub(views, "views")
+//	r, err := kokoro.NewHTMLRenderer(sub, kokoro.HTMLConfig{Layout: "layouts/main"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	s.Renderer = r
+//
+// For hot reload during development, load the templates from disk with
+// os.DirFS("views") and set Reload.
+func NewHTMLRenderer(fsys fs.FS, config ...HTMLConfig) (*HTMLRenderer, error) {
+	cfg := HTMLConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Extension == "" {
+		cfg.Extension = ".html"
+	}
+
+	r := &HTMLRenderer{fsys: fsys, cfg: cfg}
+	pages, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.pages = pages
+	return r, nil
+}
+
+// Render implements Renderer.
+func (r *HTMLRenderer) Render(w io.Writer, name string, data any, c *Context) error {
+	pages := r.pages
+	if r.cfg.Reload {
+		var err error
+		if pages, err = r.load(); err != nil {
+			return err
+		}
+	}
+	page, ok := pages[name]
+	if !ok {
+		return fmt.Errorf("kokoro: template %q not found", name)
+	}
+
+	t, _ := page.pool.Get().(*template.Template)
+	if t == nil {
+		var err error
+		if t, err = page.tmpl.Clone(); err != nil {
+			return err
+		}
+	}
+	defer page.pool.Put(t)
+
+	funcs := template.FuncMap{}
+	if _, ok := r.cfg.Funcs["url"]; !ok && c != nil {
+		funcs["url"] = func(pattern string, params ...any) (string, error) {
+			if !c.server.hasRoute(pattern) {
+				return "", fmt.Errorf("kokoro: no route registered with pattern %q", pattern)
+			}
+			return ReversePath(pattern, params...)
+		}
+	}
+	if _, ok := r.cfg.Funcs["csrf"]; !ok {
+		funcs["csrf"] = func() string {
+			if c == nil {
+				return ""
+			}
+			return c.CSRFToken()
+		}
+	}
+	return t.Funcs(funcs).ExecuteTemplate(w, page.root, data)
+}
+
+// load parses the templates of the file system into a set per page.
+func (r *HTMLRenderer) load() (map[string]*htmlPage, error) {
+	shared := template.New("").Funcs(template.FuncMap{
+		"url":  ReversePath,                 // Checked against the Server's routes in Render.
+		"csrf": func() string { return "" }, // Bound to the request in Render.
+	}).Funcs(r.cfg.Funcs)
+
+	sources := make(map[string]string)
+	err := fs.WalkDir(r.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != r.cfg.Extension {
+			return err
+		}
+		data, err := fs.ReadFile(r.fsys, p)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(p, r.cfg.Extension)
+		if isSharedTemplate(name) {
+			if _, err := shared.New(name).Parse(string(data)); err != nil {
+				return err
+			}
+			return nil
+		}
+		sources[name] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r.cfg.Layout != "" && shared.Lookup(r.cfg.Layout) == nil {
+		return nil, fmt.Errorf("kokoro: layout %q not found", r.cfg.Layout)
+	}
+
+	pages := make(map[string]*htmlPage, len(sources))
+	for name, source := range sources {
+		set, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+		t, err := set.New(name).Parse(source)
+		if err != nil {
+			return nil, err
+		}
+		root := name
+		if r.cfg.Layout != "" {
+			if _, err := set.AddParseTree("content", t.Tree); err != nil {
+				return nil, err
+			}
+			root = r.cfg.Layout
+		}
+		pages[name] = &htmlPage{tmpl: set, root: root}
+	}
+	for _, t := range shared.Templates() {
+		if strings.HasPrefix(t.Name(), "partials/") {
+			pages[t.Name()] = &htmlPage{tmpl: shared, root: t.Name()}
+		}
+	}
+	return pages, nil
+}
+
+// isSharedTemplate reports whether the template called name is a layout or a
+// partial, shared with every page.
+func isSharedTemplate(name string) bool {
+	return strings.HasPrefix(name, "layouts/") || strings.HasPrefix(name, "partials/")
+}
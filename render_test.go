@@ -0,0 +1,116 @@
+package kokoro
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestHTMLRenderer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html": {Data: []byte(`<title>{{ block "title" . }}Default{{ end }}</title>{{ template "partials/nav" . }}<main>{{ template "content" . }}</main>`)},
+		"partials/nav.html": {Data: []byte(`<nav>{{ .Name }}</nav>`)},
+		"home.html":         {Data: []byte(`home`)},
+		"users/show.html":   {Data: []byte(`{{ define "title" }}User {{ .Name }}{{ end }}<a href="{{ url "/users/{id:int}" "id" .ID }}">{{ up .Name }}</a>`)},
+		"users/form.html":   {Data: []byte(`<input value="{{ csrf }}">`)},
+		"admin/link.html":   {Data: []byte(`{{ url "/admin/users/{id}" "id" 1 }}`)},
+		"tenant/link.html":  {Data: []byte(`{{ url "/files/{path:*}" "path" "a/b.txt" }}`)},
+		"stale/link.html":   {Data: []byte(`{{ url "/people/{id}" "id" 1 }}`)},
+		"ignored.txt":       {Data: []byte(`x`)},
+	}
+	r, err := NewHTMLRenderer(fsys, HTMLConfig{Layout: "layouts/main", Funcs: map[string]any{"up": strings.ToUpper}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New()
+	s.Renderer = r
+	handler := func(c *Context) error {
+		c.SetCSRFToken("tok<>")
+		return c.Render(c.Query("page"), map[string]any{"Name": "<b>Al</b>", "ID": c.Query("id")})
+	}
+	s.GET("/users/{id:int}", handler)
+	s.Group("/admin").GET("/users/{id}", handler)
+	s.Host("files.example.com").GET("/files/{path:*}", handler)
+
+	tests := []struct {
+		name    string
+		page    string
+		id      string
+		body    string
+		wantErr bool
+	}{
+		{"layout", "home", "", `<title>Default</title><nav>&lt;b&gt;Al&lt;/b&gt;</nav><main>home</main>`, false},
+		{"block and url", "users/show", "42", `<title>User &lt;b&gt;Al&lt;/b&gt;</title><nav>&lt;b&gt;Al&lt;/b&gt;</nav><main><a href="/users/42">&lt;B&gt;AL&lt;/B&gt;</a></main>`, false},
+		{"csrf", "users/form", "", `<main><input value="tok&lt;&gt;"></main>`, false},
+		{"group prefix", "admin/link", "", `<main>/admin/users/1</main>`, false},
+		{"host route", "tenant/link", "", `<main>/files/a/b.txt</main>`, false},
+		{"unregistered pattern", "stale/link", "", "", true},
+		{"constraint mismatch", "users/show", "abc", "", true},
+		{"missing page", "nope", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled error
+			s.SetErrorHandler(func(c *Context, err error) error {
+				handled = err
+				return defaultErrorHandler(c, err)
+			})
+			var ctx fasthttp.RequestCtx
+			ctx.Request.SetRequestURI("/users/1?page=" + tt.page + "&id=" + tt.id)
+			s.Handler(&ctx)
+
+			if (handled != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", handled, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := string(ctx.Response.Header.ContentType()); got != "text/html; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if got := string(ctx.Response.Body()); !strings.Contains(got, tt.body) {
+				t.Errorf("body = %q, want it to contain %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestHTMLRendererConfig(t *testing.T) {
+	if _, err := NewHTMLRenderer(fstest.MapFS{"a.html": {Data: []byte(`a`)}}, HTMLConfig{Layout: "layouts/missing"}); err == nil {
+		t.Error("NewHTMLRenderer with a missing layout succeeded")
+	}
+	if _, err := NewHTMLRenderer(fstest.MapFS{"a.html": {Data: []byte(`{{ end }}`)}}, HTMLConfig{}); err == nil {
+		t.Error("NewHTMLRenderer with a malformed template succeeded")
+	}
+}
+
+func TestHTMLRendererReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(data string) {
+		if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1")
+	r, err := NewHTMLRenderer(os.DirFS(dir), HTMLConfig{Reload: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New()
+	s.Renderer = r
+	s.GET("/", func(c *Context) error { return c.Render("page", nil) })
+
+	for _, want := range []string{"v1", "v2"} {
+		write(want)
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI("/")
+		s.Handler(&ctx)
+		if got := string(ctx.Response.Body()); got != want {
+			t.Errorf("body = %q, want %q", got, want)
+		}
+	}
+}
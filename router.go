@@ -1,6 +1,7 @@
 package kokoro
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/fasthttp/router"
@@ -15,6 +16,8 @@ type Router struct {
 	globalMiddlewares []middlewareFunc
 	basePath          string
 	server            *Server
+	parent            *Router
+	errorHandler      ErrorHandler
 }
 
 // NewRouter creates and returns a new Router instance with a new underlying fasthttp router.
@@ -36,9 +39,33 @@ func (r *Router) Group(prefix string) *Router {
 		globalMiddlewares: r.globalMiddlewares,
 		basePath:          r.basePath + prefix,
 		server:            r.server,
+		parent:            r,
 	}
 }
 
+// SetErrorHandler sets the handler for errors returned by the routes of this group,
+// including routes of its sub-groups that do not set their own, and for the 404 and
+// 405 errors of requests below the group's prefix.
+// If the handler returns an error, it is passed on to the parent group's handler,
+// and ultimately to the Server's error handler.
+//
+// Example:
+//
+//	web := s.Group("/web")
+//	web.SetErrorHandler(func(c *kokoro.Context, err error) error {
+//	    if c.Accepts("text/html") == "" {
+//	        return err // let the parent handler render it
+//	    }
+//	    return c.Status(500).SendText("Something went wrong")
+//	})
+func (r *Router) SetErrorHandler(h ErrorHandler) *Router {
+	r.errorHandler = h
+	if r.server != nil && !slices.Contains(r.server.errorGroups, r) {
+		r.server.errorGroups = append(r.server.errorGroups, r)
+	}
+	return r
+}
+
 // Route creates a group of routes with a common prefix.
 // It accepts a function in which routes can be registered on the grouped router.
 func (r *Router) Route(prefix string, fn func(*Router)) {
@@ -112,11 +139,15 @@ func (r *Router) Any(path string, handler HandlerFunc, mws ...NextMiddleware) {
 // add is a helper to register a route with the given method, path,
 // handler, and optional route-specific middlewares.
 func (r *Router) add(method string, path string, handler HandlerFunc, mws ...NextMiddleware) {
-	fullPath := strings.TrimRight(r.basePath, "/") + "/" + strings.TrimLeft(path, "/")
+	pattern := strings.TrimRight(r.basePath, "/") + "/" + strings.TrimLeft(path, "/")
 	routeMws := convertNext(mws...)
 	allMws := append(r.globalMiddlewares, routeMws...)
 	finalHandler := chainMiddlewares(handler, allMws...)
-	r.r.Handle(method, fullPath, r.server.wrap(finalHandler))
+	path = expandParamConstraints(pattern)
+	r.r.Handle(method, path, r.server.wrapGroup(r, pattern, finalHandler))
+	if method != MethodOptions {
+		r.server.addAutoOptions(r, pattern, path, allMws)
+	}
 }
 
 // ServeFile returns HTTP response containing compressed file contents
@@ -129,7 +160,7 @@ func (r *Router) add(method string, path string, handler HandlerFunc, mws ...Nex
 //
 // Directory contents is returned if path points to directory.
 func (r *Router) ServeFile(path, filepath string) {
-	r.GET(r.basePath+path, func(ctx *Context) error {
+	r.GET(path, func(ctx *Context) error {
 		return ctx.SendFile(filepath)
 	})
 }
@@ -173,3 +204,19 @@ func wrapNext(m NextMiddleware) middlewareFunc {
 		}
 	}
 }
+
+// setAllow completes the Allow header computed by the underlying router, which
+// only lists explicitly registered methods, with the HEAD method derived from GET routes.
+func (c *Context) setAllow() {
+	allow := string(c.ctx.Response.Header.Peek(HeaderAllow))
+	if allow == "" {
+		return
+	}
+	methods := strings.Split(allow, ", ")
+	if !slices.Contains(methods, MethodGet) || slices.Contains(methods, MethodHead) {
+		return
+	}
+	methods = append(methods, MethodHead)
+	slices.Sort(methods)
+	c.ctx.Response.Header.Set(HeaderAllow, strings.Join(methods, ", "))
+}
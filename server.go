@@ -1,10 +1,14 @@
 package kokoro
 
 import (
+	"errors"
+	"log/slog"
 	"net"
 	"strings"
+	"sync"
 	"unsafe"
 
+	"github.com/fasthttp/router"
 	"github.com/savsgio/gotils/nocopy"
 	"github.com/valyala/fasthttp"
 )
@@ -12,19 +16,34 @@ import (
 type Server struct {
 	noCopy nocopy.NoCopy // nolint:structcheck,unused
 	*Router
-	errorHandler   ErrorHandler
-	zeroAllocation bool
-	JsonEncoder    EncoderFunc
-	JsonDecoder    DecoderFunc
-	XmlEncoder     EncoderFunc
-	XmlDecoder     DecoderFunc
-	YamlEncoder    EncoderFunc
-	YamlDecoder    DecoderFunc
-	TomlEncoder    EncoderFunc
-	TomlDecoder    DecoderFunc
-	CbarEncoder    EncoderFunc
-	CabarDecoder   DecoderFunc
-	TrustedProxies []string
+	errorHandler       ErrorHandler
+	zeroAllocation     bool
+	JsonEncoder        EncoderFunc
+	JsonDecoder        DecoderFunc
+	XmlEncoder         EncoderFunc
+	XmlDecoder         DecoderFunc
+	YamlEncoder        EncoderFunc
+	YamlDecoder        DecoderFunc
+	TomlEncoder        EncoderFunc
+	TomlDecoder        DecoderFunc
+	CbarEncoder        EncoderFunc
+	CabarDecoder       DecoderFunc
+	TrustedProxies     []string         // CIDR ranges or IPs of proxies whose forwarding headers are honored; read once, when first needed.
+	ProxyHeaders       []string         // Headers read for the client IP behind a trusted proxy; Forwarded, X-Forwarded-For and X-Real-IP if empty.
+	Logger             *slog.Logger     // Base logger returned by Context.Logger; slog.Default() if nil.
+	Client             *fasthttp.Client // Client used by Context.Do; fasthttp's default client if nil.
+	StreamRequestBody  bool             // Stream large request bodies to handlers instead of buffering them, unless read whole first; see Context.MultipartReader.
+	MaxRequestBodySize int              // Largest request body read into memory; fasthttp's 4 MB default if 0. Larger bodies are rejected with 413, or streamed with StreamRequestBody.
+	Renderer           Renderer         // Renders the templates of Context.Render; see NewHTMLRenderer.
+	hosts              []*hostRoute
+	pathPolicies       []pathPolicyEntry
+	errorMappings      []errorMapping
+	errorGroups        []*Router
+	recoverPanics      bool
+	panicHook          func(*Context, *PanicError)
+	autoOptions        map[*router.Router][]*router.Router
+	proxyOnce          sync.Once
+	proxy              *proxyResolver
 }
 
 func New() *Server {
@@ -32,6 +51,7 @@ func New() *Server {
 		Router:         NewRouter(),
 		errorHandler:   defaultErrorHandler,
 		zeroAllocation: true,
+		recoverPanics:  true,
 		JsonEncoder:    defaultJsonEncoder,
 		JsonDecoder:    defaultJsonDecoder,
 		XmlEncoder:     defaultXMLEncoder,
@@ -45,47 +65,136 @@ func New() *Server {
 	}
 	s.Router.server = s
 
-	s.r.NotFound = s.wrap(func(c *Context) error {
-		return &HTTPError{Code: 404, Message: "Not Found"}
-	})
+	// Path corrections are resolved by fixPath according to the group's PathPolicy.
+	s.r.RedirectTrailingSlash = false
+	s.r.RedirectFixedPath = false
+
+	s.r.NotFound = func(fctx *fasthttp.RequestCtx) {
+		if !s.fixPath(s.r, fctx) {
+			s.serve(fctx, s.errorGroup(s.Router, fctx), "", notFound)
+		}
+	}
+
+	s.r.MethodNotAllowed = func(fctx *fasthttp.RequestCtx) {
+		s.serve(fctx, s.errorGroup(s.Router, fctx), "", methodNotAllowed)
+	}
 
-	s.r.MethodNotAllowed = s.wrap(func(c *Context) error {
-		return &HTTPError{Code: 405, Message: "Method Not Allowed"}
+	s.r.GlobalOPTIONS = s.wrap(func(c *Context) error {
+		c.setAllow()
+		return c.SendStatusCode(StatusNoContent)
 	})
 
 	return s
 }
 
-func (s *Server) isTrustedProxy(ip net.IP) bool {
-	for _, cidr := range s.TrustedProxies {
-		if strings.Contains(cidr, "/") {
-			_, subnet, err := net.ParseCIDR(cidr)
-			if err == nil && subnet.Contains(ip) {
-				return true
-			}
-		} else if ip.String() == cidr {
-			return true
-		}
-	}
-	return false
-}
-
 func (s *Server) WithZeroAllocation(value bool) *Server {
 	s.zeroAllocation = value
 	return s
 }
 
 func (s *Server) wrap(h HandlerFunc) fasthttp.RequestHandler {
+	return s.wrapGroup(s.Router, "", h)
+}
+
+// wrapGroup adapts h, registered under the route pattern, into a fasthttp.RequestHandler
+// whose errors are handled by the error handlers of the given Router group.
+func (s *Server) wrapGroup(r *Router, route string, h HandlerFunc) fasthttp.RequestHandler {
 	return func(fctx *fasthttp.RequestCtx) {
-		ctx := acquireContext(fctx, s)
-		if err := h(ctx); err != nil {
-			err := s.errorHandler(ctx, err)
-			if err != nil {
-				_ = ctx.SendStatusCode(StatusInternalServerError) // we can not do any thing here
-			}
+		s.serve(fctx, r, route, h)
+	}
+}
+
+// serve runs h for the request, handling its errors with the error handlers of
+// the given Router group.
+func (s *Server) serve(fctx *fasthttp.RequestCtx, r *Router, route string, h HandlerFunc) {
+	ctx := acquireContext(fctx, s)
+	ctx.group = r
+	ctx.route = route
+	defer releaseContext(ctx)
+	if s.recoverPanics {
+		defer s.recoverPanic(ctx)
+	}
+	if err := h(ctx); err != nil {
+		ctx.HandleError(err)
+	}
+}
+
+// notFound answers requests matching no route.
+func notFound(c *Context) error {
+	return &HTTPError{Code: StatusNotFound, Message: "Not Found"}
+}
+
+// methodNotAllowed answers requests whose path only has routes for other methods.
+func methodNotAllowed(c *Context) error {
+	c.setAllow()
+	return &HTTPError{Code: StatusMethodNotAllowed, Message: "Method Not Allowed"}
+}
+
+// errorGroup returns the innermost group sharing root's router whose prefix contains
+// the request path and that has an error handler, or root if there is none. Errors
+// raised without a matched route, such as 404 and 405, are thus rendered like the
+// errors of the group's routes.
+func (s *Server) errorGroup(root *Router, fctx *fasthttp.RequestCtx) *Router {
+	p := s.BytesToString(fctx.Request.URI().Path())
+	group, longest := root, -1
+	for _, g := range s.errorGroups {
+		prefix := strings.TrimRight(g.basePath, "/")
+		if g.r != root.r || len(prefix) <= longest || !hasPathPrefix(p, prefix) {
+			continue
+		}
+		group, longest = g, len(prefix)
+	}
+	return group
+}
+
+// handleError passes err to the error handlers of the Router group and its parents,
+// innermost first, and finally to the Server's error handler. A handler that
+// returns an error falls back to the next one with that error.
+func (s *Server) handleError(c *Context, r *Router, err error) {
+	for ; r != nil; r = r.parent {
+		if r.errorHandler == nil {
+			continue
+		}
+		if err = r.errorHandler(c, err); err == nil {
+			return
+		}
+	}
+	if err = s.errorHandler(c, err); err != nil {
+		_ = c.SendStatusCode(StatusInternalServerError) // we can not do any thing here
+	}
+}
+
+// SetErrorHandler sets the handler for errors returned by handlers and middlewares.
+// It is used for every route whose groups' error handlers did not handle the error.
+// Passing nil restores the default handler, which renders problem details.
+func (s *Server) SetErrorHandler(h ErrorHandler) *Server {
+	if h == nil {
+		h = defaultErrorHandler
+	}
+	s.errorHandler = h
+	return s
+}
+
+// dispatch routes the request through the given router. HEAD requests without
+// an explicit HEAD route are served by the matching GET route; fasthttp omits
+// the body of responses to HEAD requests. OPTIONS requests without an explicit
+// OPTIONS route are answered through the middlewares of the matching routes.
+func (s *Server) dispatch(r *router.Router, fctx *fasthttp.RequestCtx) {
+	switch {
+	case fctx.IsHead():
+		path := s.BytesToString(fctx.Request.URI().PathOriginal())
+		if h := s.lookup(r, MethodHead, path, fctx); h != nil {
+			h(fctx)
+			return
+		}
+	case fctx.IsOptions():
+		path := s.BytesToString(fctx.Request.URI().PathOriginal())
+		if h := s.lookupOptions(r, path, fctx); h != nil {
+			h(fctx)
+			return
 		}
-		releaseContext(ctx)
 	}
+	r.Handler(fctx)
 }
 
 func (s *Server) BytesToString(value []byte) string {
@@ -96,12 +205,33 @@ func (s *Server) BytesToString(value []byte) string {
 }
 
 func (s *Server) Listen(addr string) error {
-	return fasthttp.ListenAndServe(addr, s.r.Handler)
+	return s.fasthttpServer().ListenAndServe(addr)
+}
+
+// fasthttpServer returns the fasthttp.Server serving s with its settings.
+func (s *Server) fasthttpServer() *fasthttp.Server {
+	return &fasthttp.Server{
+		Handler:            s.Handler,
+		StreamRequestBody:  s.StreamRequestBody,
+		MaxRequestBodySize: s.MaxRequestBodySize,
+		ErrorHandler:       connErrorHandler,
+	}
 }
 
-func defaultErrorHandler(c *Context, err error) error {
-	if e, ok := err.(*HTTPError); ok {
-		return c.Status(e.Code).SendJSON(H{"message": e.Message})
+// connErrorHandler answers requests fasthttp fails to read before they reach the
+// Server. It differs from fasthttp's default only in rejecting bodies larger than
+// MaxRequestBodySize with 413 instead of 400.
+func connErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	var small *fasthttp.ErrSmallBuffer
+	var netErr *net.OpError
+	switch {
+	case errors.Is(err, fasthttp.ErrBodyTooLarge):
+		ctx.Error("Request body too large", StatusPayloadTooLarge)
+	case errors.As(err, &small):
+		ctx.Error("Too big request header", StatusRequestHeaderFieldsTooLarge)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		ctx.Error("Request timeout", StatusRequestTimeout)
+	default:
+		ctx.Error("Error when parsing request", StatusBadRequest)
 	}
-	return c.Status(StatusInternalServerError).SendJSON(H{"message": "Internal Server Error"})
 }
@@ -0,0 +1,67 @@
+package kokoro
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestMaxRequestBodySize(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream bool
+		size   int
+		status int
+		body   string // Bytes read by the handler, and whether they were streamed.
+	}{
+		{"within limit", false, 64, StatusOK, "64 buffered"},
+		{"at limit", false, 100, StatusOK, "100 buffered"},
+		{"too large", false, 101, StatusPayloadTooLarge, "Request body too large"},
+		{"streamed within limit", true, 64, StatusOK, "64 streamed"},
+		{"streamed beyond limit", true, 10_000, StatusOK, "10000 streamed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.MaxRequestBodySize = 100
+			s.StreamRequestBody = tt.stream
+			s.POST("/", func(c *Context) error {
+				if !c.RequestCtx().Request.IsBodyStream() {
+					return c.SendText(strconv.Itoa(len(c.PostBody())) + " buffered")
+				}
+				body, err := io.ReadAll(c.RequestCtx().RequestBodyStream())
+				if err != nil {
+					return err
+				}
+				return c.SendText(strconv.Itoa(len(body)) + " streamed")
+			})
+
+			ln := fasthttputil.NewInmemoryListener()
+			defer ln.Close()
+			go s.fasthttpServer().Serve(ln)
+			client := &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+
+			req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(req)
+			defer fasthttp.ReleaseResponse(resp)
+			req.SetRequestURI("http://example.com/")
+			req.Header.SetMethod(fasthttp.MethodPost)
+			req.SetBodyString(strings.Repeat("x", tt.size))
+			if err := client.Do(req, resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resp.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			if got := string(resp.Body()); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package kokoro
+
+import (
+	"errors"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// StaticConfig configures how Router.Static and Router.StaticFS serve files.
+type StaticConfig struct {
+	// Index lists the file names served when a directory is requested.
+	// Defaults to ["index.html"].
+	Index []string
+
+	// Browse enables an HTML listing for directories without an index file.
+	// When disabled, such requests are answered with 404.
+	Browse bool
+
+	// CacheControl maps file extensions (including the dot, e.g. ".js") to the
+	// Cache-Control header sent with matching files. The "*" key applies to
+	// every extension without its own entry.
+	CacheControl map[string]string
+
+	// Compress serves precompressed sidecar files, e.g. "app.js.br" or "app.js.gz"
+	// next to "app.js", when the client accepts the corresponding encoding.
+	Compress bool
+
+	// SPA serves the root index file for paths that do not exist, so client-side
+	// routers of single-page applications receive every unknown URL.
+	SPA bool
+}
+
+// Static serves the files under the root directory on disk at the given prefix.
+//
+// For example, Static("/assets", "./public") serves "./public/css/app.css"
+// at "/assets/css/app.css". Paths are cleaned before use, so requests can
+// never escape root.
+func (r *Router) Static(prefix, root string, config ...StaticConfig) {
+	r.StaticFS(prefix, os.DirFS(root), config...)
+}
+
+// StaticFS serves the files of the given fs.FS at the given prefix.
+// It works with any file system, including embed.FS:
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	sub, _ := fs.Sub(public, "public")
+//	s.StaticFS("/", sub, kokoro.StaticConfig{SPA: true})
+func (r *Router) StaticFS(prefix string, fsys fs.FS, config ...StaticConfig) {
+	cfg := StaticConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if len(cfg.Index) == 0 {
+		cfg.Index = []string{"index.html"}
+	}
+
+	handler := func(c *Context) error {
+		return serveStatic(c, fsys, &cfg, c.Param("filepath"))
+	}
+
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix != "" {
+		r.GET(prefix, handler)
+	}
+	r.GET(prefix+"/{filepath:*}", handler)
+}
+
+// serveStatic resolves the requested name inside fsys and writes the matching
+// file, directory index or listing to the response.
+func serveStatic(c *Context, fsys fs.FS, cfg *StaticConfig, name string) error {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			if cfg.SPA {
+				if index, ok := findIndex(fsys, ".", cfg.Index); ok {
+					return sendStaticFile(c, fsys, cfg, index)
+				}
+			}
+			return &HTTPError{Code: StatusNotFound, Message: "Not Found"}
+		}
+		return err
+	}
+
+	if !info.IsDir() {
+		return sendStaticFile(c, fsys, cfg, name)
+	}
+
+	if index, ok := findIndex(fsys, name, cfg.Index); ok {
+		return sendStaticFile(c, fsys, cfg, index)
+	}
+	if cfg.Browse {
+		return sendDirListing(c, fsys, name)
+	}
+	if cfg.SPA {
+		if index, ok := findIndex(fsys, ".", cfg.Index); ok {
+			return sendStaticFile(c, fsys, cfg, index)
+		}
+	}
+	return &HTTPError{Code: StatusNotFound, Message: "Not Found"}
+}
+
+// findIndex returns the path of the first index file present in dir.
+func findIndex(fsys fs.FS, dir string, index []string) (string, bool) {
+	for _, name := range index {
+		p := path.Join(dir, name)
+		if info, err := fs.Stat(fsys, p); err == nil && !info.IsDir() {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// staticEncodings lists the precompressed sidecar encodings in order of preference,
+// along with the file suffix used to store them.
+var staticEncodings = []struct {
+	encoding string
+	suffix   string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// sendStaticFile streams the named file, or its precompressed sidecar, to the client.
+func sendStaticFile(c *Context, fsys fs.FS, cfg *StaticConfig, name string) error {
+	ext := path.Ext(name)
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	served := name
+	if cfg.Compress {
+		c.Vary(HeaderAcceptEncoding)
+		for _, e := range staticEncodings {
+			if c.AcceptsEncoding(e.encoding) == "" {
+				continue
+			}
+			if info, err := fs.Stat(fsys, name+e.suffix); err == nil && !info.IsDir() {
+				served = name + e.suffix
+				c.ctx.Response.Header.Set(HeaderContentEncoding, e.encoding)
+				break
+			}
+		}
+	}
+
+	f, err := fsys.Open(served)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if value, ok := cfg.CacheControl[ext]; ok {
+		c.ctx.Response.Header.Set(HeaderCacheControl, value)
+	} else if value, ok := cfg.CacheControl["*"]; ok {
+		c.ctx.Response.Header.Set(HeaderCacheControl, value)
+	}
+	if modTime := info.ModTime(); !modTime.IsZero() {
+		c.ctx.Response.Header.Set(HeaderLastModified, modTime.UTC().Format(http.TimeFormat))
+	}
+	c.ContentType(contentType)
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		return c.SendRange(rs, info.Size(), info.ModTime())
+	}
+	// fasthttp closes the stream once the body has been written.
+	c.ctx.SetBodyStream(f, int(info.Size()))
+	return nil
+}
+
+// sendDirListing renders a minimal HTML listing of the directory's entries.
+func sendDirListing(c *Context, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	base := c.Path()
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>Index of ")
+	b.WriteString(html.EscapeString(base))
+	b.WriteString("</title></head><body><h1>Index of ")
+	b.WriteString(html.EscapeString(base))
+	b.WriteString("</h1><ul>\n")
+	if dir != "." {
+		b.WriteString("<li><a href=\"")
+		b.WriteString(html.EscapeString(base))
+		b.WriteString("../\">../</a></li>\n")
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		b.WriteString("<li><a href=\"")
+		b.WriteString(html.EscapeString(base + (&url.URL{Path: name}).EscapedPath()))
+		b.WriteString("\">")
+		b.WriteString(html.EscapeString(name))
+		b.WriteString("</a></li>\n")
+	}
+	b.WriteString("</ul></body></html>\n")
+
+	c.ContentType("text/html; charset=utf-8")
+	c.ctx.Response.SetBodyString(b.String())
+	return nil
+}
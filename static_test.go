@@ -0,0 +1,104 @@
+package kokoro
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestStatic(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>home</h1>")},
+		"css/app.css":     {Data: []byte("body{}")},
+		"js/app.js":       {Data: []byte("plain js")},
+		"js/app.js.br":    {Data: []byte("brotli js")},
+		"js/app.js.gz":    {Data: []byte("gzip js")},
+		"docs/a b.txt":    {Data: []byte("a")},
+		"docs/<b>.txt":    {Data: []byte("b")},
+		"blog/index.html": {Data: []byte("blog")},
+		"data.bin":        {Data: []byte("0123456789")},
+	}
+	dir := t.TempDir()
+	for name, data := range map[string]string{"public/index.html": "disk home", "secret.txt": "secret"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New()
+	s.StaticFS("/assets", fsys)
+	s.StaticFS("/browse", fsys, StaticConfig{Browse: true})
+	s.StaticFS("/spa", fsys, StaticConfig{SPA: true})
+	s.StaticFS("/cached", fsys, StaticConfig{Compress: true, CacheControl: map[string]string{".css": "max-age=3600", "*": "no-cache"}})
+	s.Static("/disk", filepath.Join(dir, "public"))
+
+	tests := []struct {
+		name    string
+		path    string
+		headers [][2]string
+		status  int
+		body    string      // Expected to be contained in the body.
+		want    [][2]string // Expected response headers.
+	}{
+		{"file", "/assets/css/app.css", nil, StatusOK, "body{}", [][2]string{{HeaderContentType, "text/css; charset=utf-8"}}},
+		{"root index", "/assets", nil, StatusOK, "<h1>home</h1>", [][2]string{{HeaderContentType, "text/html; charset=utf-8"}}},
+		{"root with slash redirected", "/assets/", nil, StatusMovedPermanently, "", [][2]string{{HeaderLocation, "http://example.com/assets"}}},
+		{"directory index", "/assets/blog/", nil, StatusOK, "blog", nil},
+		{"unknown type", "/assets/data.bin", nil, StatusOK, "0123456789", [][2]string{{HeaderContentType, "application/octet-stream"}}},
+		{"range", "/assets/data.bin", [][2]string{{HeaderRange, "bytes=2-4"}}, StatusPartialContent, "234", [][2]string{{HeaderContentRange, "bytes 2-4/10"}}},
+		{"missing", "/assets/missing.css", nil, StatusNotFound, "", nil},
+		{"directory without index", "/assets/docs/", nil, StatusNotFound, "", nil},
+		{"listing", "/browse/docs/", nil, StatusOK, `<a href="/browse/docs/a%20b.txt">a b.txt</a>`, nil},
+		{"listing escapes names", "/browse/docs", nil, StatusOK, `&lt;b&gt;.txt</a>`, nil},
+		{"listing parent", "/browse/docs/", nil, StatusOK, `<a href="/browse/docs/../">../</a>`, nil},
+		{"spa fallback", "/spa/users/42", nil, StatusOK, "<h1>home</h1>", nil},
+		{"spa directory", "/spa/docs", nil, StatusOK, "<h1>home</h1>", nil},
+		{"spa file", "/spa/css/app.css", nil, StatusOK, "body{}", nil},
+		{"cache by extension", "/cached/css/app.css", nil, StatusOK, "body{}", [][2]string{{HeaderCacheControl, "max-age=3600"}}},
+		{"cache default", "/cached/index.html", nil, StatusOK, "home", [][2]string{{HeaderCacheControl, "no-cache"}}},
+		{"precompressed brotli", "/cached/js/app.js", [][2]string{{HeaderAcceptEncoding, "gzip, br"}}, StatusOK, "brotli js", [][2]string{{HeaderContentEncoding, "br"}, {HeaderContentType, "text/javascript; charset=utf-8"}, {HeaderVary, HeaderAcceptEncoding}}},
+		{"precompressed gzip", "/cached/js/app.js", [][2]string{{HeaderAcceptEncoding, "gzip"}}, StatusOK, "gzip js", [][2]string{{HeaderContentEncoding, "gzip"}}},
+		{"brotli refused", "/cached/js/app.js", [][2]string{{HeaderAcceptEncoding, "br;q=0, gzip"}}, StatusOK, "gzip js", [][2]string{{HeaderContentEncoding, "gzip"}}},
+		{"uncompressed", "/cached/js/app.js", nil, StatusOK, "plain js", [][2]string{{HeaderContentEncoding, ""}, {HeaderVary, HeaderAcceptEncoding}}},
+		{"no sidecar", "/cached/css/app.css", [][2]string{{HeaderAcceptEncoding, "br"}}, StatusOK, "body{}", [][2]string{{HeaderContentEncoding, ""}}},
+		{"disk", "/disk", nil, StatusOK, "disk home", nil},
+		{"disk traversal", "/disk/../secret.txt", nil, StatusNotFound, "", nil},
+		{"disk encoded traversal", "/disk/%2e%2e/secret.txt", nil, StatusNotFound, "", nil},
+		{"disk encoded slash traversal", "/disk/..%2fsecret.txt", nil, StatusNotFound, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetHost("example.com")
+			ctx.Request.SetRequestURI(tt.path)
+			for _, h := range tt.headers {
+				ctx.Request.Header.Set(h[0], h[1])
+			}
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+			body := string(ctx.Response.Body())
+			if strings.Contains(body, "secret") {
+				t.Fatalf("body = %q leaks a file outside the root", body)
+			}
+			if !strings.Contains(body, tt.body) {
+				t.Errorf("body = %q, want it to contain %q", body, tt.body)
+			}
+			for _, h := range tt.want {
+				if got := string(ctx.Response.Header.Peek(h[0])); got != h[1] {
+					t.Errorf("%s = %q, want %q", h[0], got, h[1])
+				}
+			}
+		})
+	}
+}
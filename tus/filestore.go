@@ -0,0 +1,190 @@
+package tus
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileStore is a Store keeping uploads in a directory of the local file system.
+// Each upload is made of two files: "<id>" holding the received bytes, whose size
+// is the offset of the upload, and "<id>.info" holding its description as JSON.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore keeping uploads in dir, which is created if
+// it does not exist.
+//
+// Example:
+//
+//	store, err := tus.NewFileStore("./uploads")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Path returns the path of the file holding the bytes of the upload with the
+// given id, e.g. to move it elsewhere once complete.
+func (s *FileStore) Path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// Create implements Store.
+func (s *FileStore) Create(info Info) error {
+	if !validID(info.ID) {
+		return errors.New("tus: invalid upload id " + info.ID)
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// The info file is written last: an upload only exists once it is present.
+	return os.WriteFile(s.Path(info.ID)+".info", data, 0o644)
+}
+
+// Get implements Store.
+func (s *FileStore) Get(id string) (Info, error) {
+	if !validID(id) {
+		return Info{}, ErrNotFound
+	}
+	data, err := os.ReadFile(s.Path(id) + ".info")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Info{}, ErrNotFound
+		}
+		return Info{}, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return Info{}, err
+	}
+	stat, err := os.Stat(s.Path(id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Info{}, ErrNotFound
+		}
+		return Info{}, err
+	}
+	info.ID = id
+	info.Offset = stat.Size()
+	return info, nil
+}
+
+// Write implements Store.
+func (s *FileStore) Write(id string, offset int64, r io.Reader) (n int64, err error) {
+	if !validID(id) {
+		return 0, ErrNotFound
+	}
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY, 0)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if stat.Size() != offset {
+		return 0, ErrOffsetMismatch
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err = io.Copy(f, r)
+	if errors.Is(err, ErrChecksumMismatch) {
+		if terr := f.Truncate(offset); terr != nil {
+			return 0, terr
+		}
+		return 0, err
+	}
+	return n, err
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	err := os.Remove(s.Path(id) + ".info")
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.Path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired removes the unfinished uploads whose expiration time has passed.
+// Call it periodically to reclaim the space of abandoned uploads.
+func (s *FileStore) DeleteExpired() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok {
+			continue
+		}
+		info, err := s.Get(id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return err
+		}
+		if expired(info, now) {
+			if err := s.Delete(id); err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validID reports whether id is a non-empty string of letters, digits, '-' and
+// '_', so it cannot escape the store's directory.
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_') {
+			return false
+		}
+	}
+	return true
+}
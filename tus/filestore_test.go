@@ -0,0 +1,154 @@
+package tus
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingReader returns its data, then err.
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestFileStoreWrite(t *testing.T) {
+	errRead := errors.New("connection reset")
+	tests := []struct {
+		name   string
+		id     string
+		offset int64
+		r      io.Reader
+		n      int64
+		err    error
+		stored string
+	}{
+		{"append", "upload", 5, strings.NewReader(" world"), 6, nil, "hello world"},
+		{"empty chunk", "upload", 5, strings.NewReader(""), 0, nil, "hello"},
+		{"offset mismatch", "upload", 3, strings.NewReader("lo world"), 0, ErrOffsetMismatch, "hello"},
+		{"offset beyond", "upload", 6, strings.NewReader("world"), 0, ErrOffsetMismatch, "hello"},
+		{"interrupted", "upload", 5, &failingReader{" wo", errRead}, 3, errRead, "hello wo"},
+		{"checksum mismatch", "upload", 5, &failingReader{" wo", ErrChecksumMismatch}, 0, ErrChecksumMismatch, "hello"},
+		{"unknown", "unknown", 0, strings.NewReader("x"), 0, ErrNotFound, "hello"},
+		{"invalid id", "../upload", 5, strings.NewReader("x"), 0, ErrNotFound, "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Create(Info{ID: "upload", Size: 11}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Write("upload", 0, strings.NewReader("hello")); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := store.Write(tt.id, tt.offset, tt.r)
+			if n != tt.n || !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("Write = %d, %v; want %d, %v", n, err, tt.n, tt.err)
+			}
+			if got, _ := os.ReadFile(store.Path("upload")); string(got) != tt.stored {
+				t.Errorf("stored bytes = %q, want %q", got, tt.stored)
+			}
+			info, err := store.Get("upload")
+			if err != nil || info.Offset != int64(len(tt.stored)) {
+				t.Errorf("Get = %+v, %v; want offset %d", info, err, len(tt.stored))
+			}
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir() + "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	info := Info{ID: "a-b_c", Size: 10, Metadata: map[string]string{"filename": "a.txt"}, ExpiresAt: expiresAt}
+	if err := store.Create(info); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(info); err == nil {
+		t.Error("Create of an existing upload succeeded")
+	}
+	for _, id := range []string{"", "../a", "a/b", "a.info"} {
+		if err := store.Create(Info{ID: id}); err == nil {
+			t.Errorf("Create(%q) succeeded", id)
+		}
+		if _, err := store.Get(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrNotFound", id, err)
+		}
+		if err := store.Delete(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+
+	got, err := store.Get("a-b_c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "a-b_c" || got.Size != 10 || got.Offset != 0 || got.Metadata["filename"] != "a.txt" || !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Get = %+v, want %+v", got, info)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing upload: error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Delete("a-b_c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("a-b_c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: error = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(store.Path("a-b_c")); !os.IsNotExist(err) {
+		t.Errorf("data file kept after Delete: %v", err)
+	}
+}
+
+func TestFileStoreDeleteExpired(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	uploads := []struct {
+		info Info
+		data string
+		kept bool
+	}{
+		{Info{ID: "expired", Size: 5, ExpiresAt: past}, "he", false},
+		{Info{ID: "pending", Size: 5, ExpiresAt: future}, "he", true},
+		{Info{ID: "finished", Size: 5, ExpiresAt: past}, "hello", true},
+		{Info{ID: "forever", Size: 5}, "", true},
+	}
+	for _, u := range uploads {
+		if err := store.Create(u.info); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Write(u.info.ID, 0, strings.NewReader(u.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.DeleteExpired(); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range uploads {
+		if _, err := store.Get(u.info.ID); (err == nil) != u.kept {
+			t.Errorf("%s: kept = %v, want %v", u.info.ID, err == nil, u.kept)
+		}
+	}
+}
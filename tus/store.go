@@ -0,0 +1,54 @@
+package tus
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// Errors returned by Store implementations.
+var (
+	// ErrNotFound is returned when the upload does not exist.
+	ErrNotFound = errors.New("tus: upload not found")
+
+	// ErrOffsetMismatch is returned by Store.Write when offset is not the current
+	// offset of the upload.
+	ErrOffsetMismatch = errors.New("tus: upload offset mismatch")
+
+	// ErrChecksumMismatch is returned by the reader passed to Store.Write when the
+	// chunk does not match the checksum sent by the client.
+	ErrChecksumMismatch = errors.New("tus: checksum mismatch")
+)
+
+// Info describes an upload.
+type Info struct {
+	ID        string            // Identifier of the upload, used in its URL.
+	Size      int64             // Total size of the upload, in bytes.
+	Offset    int64             // Number of bytes received so far.
+	Metadata  map[string]string // Metadata sent by the client in Upload-Metadata.
+	ExpiresAt time.Time         // Time after which an unfinished upload is discarded; zero for never.
+}
+
+// Complete reports whether all the bytes of the upload have been received.
+func (i Info) Complete() bool {
+	return i.Offset >= i.Size
+}
+
+// Store persists uploads. Implementations must be safe for concurrent use; the
+// Handler serializes writes to a given upload.
+type Store interface {
+	// Create creates an empty upload described by info, whose Offset is 0.
+	Create(info Info) error
+
+	// Get returns the upload with the given id, or ErrNotFound.
+	Get(id string) (Info, error)
+
+	// Write appends the bytes read from r to the upload, which must currently be
+	// at offset, and returns the number of bytes stored. When r fails, the bytes
+	// read so far are kept so the client can resume, unless the error wraps
+	// ErrChecksumMismatch, in which case the whole chunk is discarded.
+	Write(id string, offset int64, r io.Reader) (int64, error)
+
+	// Delete removes the upload with the given id, or returns ErrNotFound.
+	Delete(id string) error
+}
@@ -0,0 +1,455 @@
+// Package tus implements a server for tus 1.0, the open protocol for resumable
+// uploads (https://tus.io/protocols/resumable-upload), on top of Kokoro routers.
+//
+// Uploads are created with POST, resumed with PATCH requests appending bytes at
+// the offset returned by HEAD, and cancelled with DELETE. The creation,
+// termination, checksum and expiration extensions are supported.
+package tus
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Abhishek2010dev/kokoro"
+)
+
+// Version is the version of the tus protocol implemented by the Handler.
+const Version = "1.0.0"
+
+// Headers defined by the tus protocol.
+const (
+	HeaderTusResumable         = "Tus-Resumable"
+	HeaderTusVersion           = "Tus-Version"
+	HeaderTusExtension         = "Tus-Extension"
+	HeaderTusMaxSize           = "Tus-Max-Size"
+	HeaderTusChecksumAlgorithm = "Tus-Checksum-Algorithm"
+	HeaderUploadOffset         = "Upload-Offset"
+	HeaderUploadLength         = "Upload-Length"
+	HeaderUploadMetadata       = "Upload-Metadata"
+	HeaderUploadChecksum       = "Upload-Checksum"
+	HeaderUploadExpires        = "Upload-Expires"
+)
+
+// StatusChecksumMismatch is the status code answering a PATCH request whose
+// chunk does not match its Upload-Checksum header.
+const StatusChecksumMismatch = 460
+
+// ContentTypeOffsetOctetStream is the content type required for PATCH requests.
+const ContentTypeOffsetOctetStream = "application/offset+octet-stream"
+
+// extensions lists the protocol extensions supported by the Handler.
+const extensions = "creation,termination,checksum,expiration"
+
+// checksums maps the supported checksum algorithms to their hash constructors.
+var checksums = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// Config defines the configuration for the tus Handler.
+type Config struct {
+	// Store persists the uploads. It is required.
+	Store Store
+
+	// MaxSize is the maximum size of an upload, in bytes, advertised in the
+	// Tus-Max-Size header. Zero means no limit.
+	MaxSize int64
+
+	// Expiration is how long an upload may remain unfinished before it is
+	// discarded. Defaults to 24 hours; a negative value disables expiration.
+	Expiration time.Duration
+
+	// OnComplete, if set, is called once all the bytes of an upload have been
+	// received, before the response to the final PATCH request is sent. An error
+	// is returned to the client, the upload being kept.
+	OnComplete func(c *kokoro.Context, info Info) error
+}
+
+// Handler serves the tus protocol for the uploads of a Store.
+type Handler struct {
+	cfg   Config
+	locks sync.Map // Upload ids with a request in progress.
+}
+
+// New creates a tus Handler. It panics if no Store is configured.
+//
+// Example:
+//
+//	store, err := tus.NewFileStore("./uploads")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	tus.New(tus.Config{
+//	    Store:   store,
+//	    MaxSize: 1 << 30,
+//	    OnComplete: func(c *kokoro.Context, info tus.Info) error {
+//	        return os.Rename(store.Path(info.ID), "media/"+info.ID)
+//	    },
+//	}).Mount(s.Group("/files"))
+func New(config ...Config) *Handler {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Store == nil {
+		panic("tus: Config.Store is required")
+	}
+	if cfg.Expiration == 0 {
+		cfg.Expiration = 24 * time.Hour
+	}
+	return &Handler{cfg: cfg}
+}
+
+// Mount registers the routes of the tus protocol on r: uploads are created by
+// posting to the group's path and served below it, at the URL returned in the
+// Location header. Since tus clients commonly omit the trailing slash of the
+// group's path, Mount sets a PathPolicy tolerating it on r, which should
+// therefore be dedicated to uploads.
+//
+// PATCH requests carry whole chunks of the upload, which fasthttp rejects
+// beyond the Server's MaxRequestBodySize, 4 MB by default. Set StreamRequestBody
+// so chunks of any size are written to the Store as they arrive, or raise
+// MaxRequestBodySize above the chunk size of the clients.
+//
+// Browser clients need the tus headers exposed through CORS, e.g. Location,
+// Upload-Offset, Upload-Length and Tus-Resumable.
+func (h *Handler) Mount(r *kokoro.Router) {
+	r.SetPathPolicy(kokoro.PathPolicy{TrailingSlash: kokoro.PathTolerate})
+	r.OPTIONS("/", h.options)
+	r.POST("/", h.create, h.protocol)
+	r.OPTIONS("/{id}", h.options)
+	r.HEAD("/{id}", h.head, h.protocol)
+	r.PATCH("/{id}", h.patch, h.protocol)
+	r.DELETE("/{id}", h.terminate, h.protocol)
+}
+
+// protocol sets the Tus-Resumable header of the response and rejects requests
+// for another version of the protocol with 412.
+func (h *Handler) protocol(c *kokoro.Context, next kokoro.HandlerFunc) error {
+	c.SetHeader(HeaderTusResumable, Version)
+	if c.Header(HeaderTusResumable) != Version {
+		c.SetHeader(HeaderTusVersion, Version)
+		return kokoro.NewHTTPError(kokoro.StatusPreconditionFailed, "Unsupported tus version; expected "+Version)
+	}
+	return next(c)
+}
+
+// options answers discovery requests with the capabilities of the server.
+func (h *Handler) options(c *kokoro.Context) error {
+	algorithms := make([]string, 0, len(checksums))
+	for name := range checksums {
+		algorithms = append(algorithms, name)
+	}
+	sort.Strings(algorithms)
+
+	c.SetHeader(HeaderTusResumable, Version)
+	c.SetHeader(HeaderTusVersion, Version)
+	c.SetHeader(HeaderTusExtension, extensions)
+	c.SetHeader(HeaderTusChecksumAlgorithm, strings.Join(algorithms, ","))
+	if h.cfg.MaxSize > 0 {
+		c.SetHeader(HeaderTusMaxSize, strconv.FormatInt(h.cfg.MaxSize, 10))
+	}
+	return c.SendStatusCode(kokoro.StatusNoContent)
+}
+
+// create creates an upload of the length given by Upload-Length.
+func (h *Handler) create(c *kokoro.Context) error {
+	size, err := strconv.ParseInt(c.Header(HeaderUploadLength), 10, 64)
+	if err != nil || size < 0 {
+		return kokoro.NewHTTPError(kokoro.StatusBadRequest, "Missing or invalid "+HeaderUploadLength+" header")
+	}
+	if h.cfg.MaxSize > 0 && size > h.cfg.MaxSize {
+		return kokoro.NewHTTPError(kokoro.StatusPayloadTooLarge,
+			"Upload exceeds the maximum size of "+strconv.FormatInt(h.cfg.MaxSize, 10)+" bytes")
+	}
+	metadata, err := parseMetadata(c.Header(HeaderUploadMetadata))
+	if err != nil {
+		return kokoro.NewHTTPError(kokoro.StatusBadRequest, "Invalid "+HeaderUploadMetadata+" header").WithCause(err)
+	}
+
+	info := Info{ID: newID(), Size: size, Metadata: metadata}
+	if h.cfg.Expiration > 0 {
+		info.ExpiresAt = time.Now().Add(h.cfg.Expiration).Truncate(time.Second)
+	}
+	if err := h.cfg.Store.Create(info); err != nil {
+		return err
+	}
+
+	c.SetHeader(kokoro.HeaderLocation, c.BaseURL()+strings.TrimRight(c.Path(), "/")+"/"+info.ID)
+	setExpires(c, info)
+	if info.Complete() && h.cfg.OnComplete != nil {
+		if err := h.cfg.OnComplete(c, info); err != nil {
+			return err
+		}
+	}
+	return c.SendStatusCode(kokoro.StatusCreated)
+}
+
+// head reports the offset of an upload, so the client knows where to resume.
+func (h *Handler) head(c *kokoro.Context) error {
+	info, err := h.get(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	c.SetHeader(kokoro.HeaderCacheControl, "no-store")
+	c.SetHeader(HeaderUploadOffset, strconv.FormatInt(info.Offset, 10))
+	c.SetHeader(HeaderUploadLength, strconv.FormatInt(info.Size, 10))
+	if len(info.Metadata) > 0 {
+		c.SetHeader(HeaderUploadMetadata, formatMetadata(info.Metadata))
+	}
+	setExpires(c, info)
+	return c.SendStatusCode(kokoro.StatusOK)
+}
+
+// patch appends the request body to an upload at the offset given by Upload-Offset.
+func (h *Handler) patch(c *kokoro.Context) error {
+	if !strings.EqualFold(strings.TrimSpace(c.Header(kokoro.HeaderContentType)), ContentTypeOffsetOctetStream) {
+		return kokoro.NewHTTPError(kokoro.StatusUnsupportedMediaType, "Expected a "+ContentTypeOffsetOctetStream+" body")
+	}
+	offset, err := strconv.ParseInt(c.Header(HeaderUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return kokoro.NewHTTPError(kokoro.StatusBadRequest, "Missing or invalid "+HeaderUploadOffset+" header")
+	}
+	var checksum *checksumReader
+	if header := c.Header(HeaderUploadChecksum); header != "" {
+		if checksum, err = parseChecksum(header); err != nil {
+			return err
+		}
+	}
+
+	id := c.Param("id")
+	unlock, err := h.lock(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	info, err := h.get(id)
+	if err != nil {
+		return err
+	}
+	if offset != info.Offset {
+		return kokoro.NewHTTPError(kokoro.StatusConflict,
+			"Upload is at offset "+strconv.FormatInt(info.Offset, 10)).WithCause(ErrOffsetMismatch)
+	}
+	remaining := info.Size - offset
+	req := &c.RequestCtx().Request
+	if length := req.Header.ContentLength(); length > 0 && int64(length) > remaining {
+		return tooLarge(remaining)
+	}
+
+	var body io.Reader
+	if req.IsBodyStream() {
+		body = req.BodyStream()
+	} else {
+		body = bytes.NewReader(req.Body())
+	}
+	var chunk io.Reader = io.LimitReader(body, remaining)
+	if checksum != nil {
+		checksum.r = chunk
+		chunk = checksum
+	}
+
+	n, err := h.cfg.Store.Write(id, offset, chunk)
+	switch {
+	case errors.Is(err, ErrChecksumMismatch):
+		c.RequestCtx().Response.Header.SetStatusMessage([]byte("Checksum Mismatch"))
+		return (&kokoro.HTTPError{
+			Code:    StatusChecksumMismatch,
+			Title:   "Checksum Mismatch",
+			Message: "Chunk does not match its " + HeaderUploadChecksum + " header",
+		}).WithCause(err)
+	case errors.Is(err, ErrOffsetMismatch):
+		return kokoro.NewHTTPError(kokoro.StatusConflict).WithCause(err)
+	case errors.Is(err, ErrNotFound):
+		return kokoro.NewHTTPError(kokoro.StatusNotFound).WithCause(err)
+	case err != nil:
+		return err
+	}
+	info.Offset += n
+
+	c.SetHeader(HeaderUploadOffset, strconv.FormatInt(info.Offset, 10))
+	setExpires(c, info)
+	if n == remaining {
+		// The bytes received so far are kept, but the client sent more than declared.
+		if extra, _ := body.Read(make([]byte, 1)); extra > 0 {
+			return tooLarge(remaining)
+		}
+	}
+	if info.Complete() && h.cfg.OnComplete != nil {
+		if err := h.cfg.OnComplete(c, info); err != nil {
+			return err
+		}
+	}
+	return c.SendStatusCode(kokoro.StatusNoContent)
+}
+
+// terminate deletes an upload, finished or not.
+func (h *Handler) terminate(c *kokoro.Context) error {
+	id := c.Param("id")
+	unlock, err := h.lock(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := h.cfg.Store.Delete(id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return kokoro.NewHTTPError(kokoro.StatusNotFound).WithCause(err)
+		}
+		return err
+	}
+	return c.SendStatusCode(kokoro.StatusNoContent)
+}
+
+// get returns the upload with the given id, answering missing uploads with 404 and
+// expired ones, which are deleted, with 410.
+func (h *Handler) get(id string) (Info, error) {
+	info, err := h.cfg.Store.Get(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Info{}, kokoro.NewHTTPError(kokoro.StatusNotFound).WithCause(err)
+		}
+		return Info{}, err
+	}
+	if expired(info, time.Now()) {
+		if err := h.cfg.Store.Delete(id); err != nil && !errors.Is(err, ErrNotFound) {
+			return Info{}, err
+		}
+		return Info{}, kokoro.NewHTTPError(kokoro.StatusGone, "Upload expired")
+	}
+	return info, nil
+}
+
+// lock marks the upload as in use, answering concurrent requests on it with 423
+// Locked so that two clients cannot write at the same offset. The lock only
+// spans this process.
+func (h *Handler) lock(id string) (func(), error) {
+	if _, busy := h.locks.LoadOrStore(id, struct{}{}); busy {
+		return nil, kokoro.NewHTTPError(kokoro.StatusLocked, "Upload is locked by another request")
+	}
+	return func() { h.locks.Delete(id) }, nil
+}
+
+// expired reports whether info is an unfinished upload past its expiration time.
+func expired(info Info, now time.Time) bool {
+	return !info.Complete() && !info.ExpiresAt.IsZero() && now.After(info.ExpiresAt)
+}
+
+// setExpires sets the Upload-Expires header of unfinished uploads.
+func setExpires(c *kokoro.Context, info Info) {
+	if !info.Complete() && !info.ExpiresAt.IsZero() {
+		c.SetHeader(HeaderUploadExpires, info.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// tooLarge returns the 413 error for a chunk exceeding the remaining bytes.
+func tooLarge(remaining int64) error {
+	return kokoro.NewHTTPError(kokoro.StatusPayloadTooLarge,
+		"Chunk exceeds the "+strconv.FormatInt(remaining, 10)+" remaining bytes of the upload")
+}
+
+// newID returns a random upload id.
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// parseMetadata parses an Upload-Metadata header: comma-separated pairs of a key
+// and a base64-encoded value, the value being optional.
+func parseMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for pair := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" || strings.ContainsAny(value, " ") {
+			return nil, errors.New("tus: malformed metadata pair " + strconv.Quote(pair))
+		}
+		if _, dup := metadata[key]; dup {
+			return nil, errors.New("tus: duplicate metadata key " + strconv.Quote(key))
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata, nil
+}
+
+// formatMetadata formats metadata as an Upload-Metadata header, with sorted keys.
+func formatMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key
+		if value := metadata[key]; value != "" {
+			pairs[i] += " " + base64.StdEncoding.EncodeToString([]byte(value))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseChecksum parses an Upload-Checksum header, made of an algorithm name and a
+// base64-encoded digest, answering unsupported algorithms and malformed digests with 400.
+func parseChecksum(header string) (*checksumReader, error) {
+	name, digest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	newHash, ok := checksums[strings.ToLower(name)]
+	if !ok {
+		return nil, kokoro.NewHTTPError(kokoro.StatusBadRequest, "Unsupported checksum algorithm "+strconv.Quote(name))
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(digest))
+	if err != nil {
+		return nil, kokoro.NewHTTPError(kokoro.StatusBadRequest, "Invalid "+HeaderUploadChecksum+" header").WithCause(err)
+	}
+	return &checksumReader{hash: newHash(), sum: sum}, nil
+}
+
+// checksumReader hashes what is read from r, failing with ErrChecksumMismatch at
+// the end of r when the digest does not match sum. Since a chunk cannot be
+// verified when r fails, such failures also wrap ErrChecksumMismatch, so the
+// store discards the chunk.
+type checksumReader struct {
+	r    io.Reader
+	hash hash.Hash
+	sum  []byte
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.hash.Write(p[:n])
+	switch {
+	case err == io.EOF:
+		if !bytes.Equal(cr.hash.Sum(nil), cr.sum) {
+			return n, ErrChecksumMismatch
+		}
+	case err != nil:
+		return n, errors.Join(ErrChecksumMismatch, err)
+	}
+	return n, err
+}
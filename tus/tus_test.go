@@ -0,0 +1,260 @@
+package tus
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abhishek2010dev/kokoro"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestHandler(t *testing.T) {
+	sha1Sum := func(data string) string {
+		sum := sha1.Sum([]byte(data))
+		return "sha1 " + base64.StdEncoding.EncodeToString(sum[:])
+	}
+	chunk := [][2]string{{kokoro.HeaderContentType, ContentTypeOffsetOctetStream}, {HeaderUploadOffset, "5"}}
+	with := func(headers ...[2]string) [][2]string { return append(append([][2]string{}, chunk...), headers...) }
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		headers  [][2]string
+		body     string
+		status   int
+		want     [][2]string // Expected response headers.
+		stored   string      // Bytes of the "partial" upload afterwards; empty once deleted.
+		complete bool        // Whether OnComplete was called.
+	}{
+		{"discovery", kokoro.MethodOptions, "/files", nil, "", kokoro.StatusNoContent, [][2]string{
+			{HeaderTusVersion, Version},
+			{HeaderTusExtension, "creation,termination,checksum,expiration"},
+			{HeaderTusChecksumAlgorithm, "md5,sha1,sha256,sha512"},
+			{HeaderTusMaxSize, "100"},
+		}, "hello", false},
+		{"discovery on upload", kokoro.MethodOptions, "/files/partial", nil, "", kokoro.StatusNoContent, [][2]string{{HeaderTusVersion, Version}}, "hello", false},
+		{"create", kokoro.MethodPost, "/files", [][2]string{{HeaderUploadLength, "10"}}, "", kokoro.StatusCreated, [][2]string{{HeaderTusResumable, Version}}, "hello", false},
+		{"create trailing slash", kokoro.MethodPost, "/files/", [][2]string{{HeaderUploadLength, "10"}}, "", kokoro.StatusCreated, nil, "hello", false},
+		{"create empty", kokoro.MethodPost, "/files", [][2]string{{HeaderUploadLength, "0"}}, "", kokoro.StatusCreated, nil, "hello", true},
+		{"create without length", kokoro.MethodPost, "/files", nil, "", kokoro.StatusBadRequest, nil, "hello", false},
+		{"create negative length", kokoro.MethodPost, "/files", [][2]string{{HeaderUploadLength, "-1"}}, "", kokoro.StatusBadRequest, nil, "hello", false},
+		{"create too large", kokoro.MethodPost, "/files", [][2]string{{HeaderUploadLength, "101"}}, "", kokoro.StatusPayloadTooLarge, nil, "hello", false},
+		{"create bad metadata", kokoro.MethodPost, "/files", [][2]string{{HeaderUploadLength, "10"}, {HeaderUploadMetadata, "name !!"}}, "", kokoro.StatusBadRequest, nil, "hello", false},
+		{"missing version", kokoro.MethodPost, "/files", [][2]string{{HeaderTusResumable, ""}, {HeaderUploadLength, "10"}}, "", kokoro.StatusPreconditionFailed, [][2]string{{HeaderTusVersion, Version}}, "hello", false},
+		{"other version", kokoro.MethodHead, "/files/partial", [][2]string{{HeaderTusResumable, "0.2.2"}}, "", kokoro.StatusPreconditionFailed, [][2]string{{HeaderTusVersion, Version}}, "hello", false},
+		{"head", kokoro.MethodHead, "/files/partial", nil, "", kokoro.StatusOK, [][2]string{
+			{HeaderUploadOffset, "5"},
+			{HeaderUploadLength, "11"},
+			{HeaderUploadMetadata, "filename YS50eHQ="},
+			{kokoro.HeaderCacheControl, "no-store"},
+		}, "hello", false},
+		{"head unknown", kokoro.MethodHead, "/files/unknown", nil, "", kokoro.StatusNotFound, nil, "hello", false},
+		{"head expired", kokoro.MethodHead, "/files/expired", nil, "", kokoro.StatusGone, nil, "hello", false},
+		{"patch", kokoro.MethodPatch, "/files/partial", chunk, " wor", kokoro.StatusNoContent, [][2]string{{HeaderUploadOffset, "9"}}, "hello wor", false},
+		{"patch completes", kokoro.MethodPatch, "/files/partial", chunk, " world", kokoro.StatusNoContent, [][2]string{{HeaderUploadOffset, "11"}}, "hello world", true},
+		{"patch content type", kokoro.MethodPatch, "/files/partial", [][2]string{{kokoro.HeaderContentType, "application/octet-stream"}, {HeaderUploadOffset, "5"}}, " world", kokoro.StatusUnsupportedMediaType, nil, "hello", false},
+		{"patch without offset", kokoro.MethodPatch, "/files/partial", [][2]string{{kokoro.HeaderContentType, ContentTypeOffsetOctetStream}}, " world", kokoro.StatusBadRequest, nil, "hello", false},
+		{"patch offset mismatch", kokoro.MethodPatch, "/files/partial", with([2]string{HeaderUploadOffset, "3"}), "lo world", kokoro.StatusConflict, nil, "hello", false},
+		{"patch beyond length", kokoro.MethodPatch, "/files/partial", chunk, " world!", kokoro.StatusPayloadTooLarge, nil, "hello", false},
+		{"patch unknown", kokoro.MethodPatch, "/files/unknown", chunk, " world", kokoro.StatusNotFound, nil, "hello", false},
+		{"patch expired", kokoro.MethodPatch, "/files/expired", with([2]string{HeaderUploadOffset, "0"}), "hello", kokoro.StatusGone, nil, "hello", false},
+		{"patch checksum", kokoro.MethodPatch, "/files/partial", with([2]string{HeaderUploadChecksum, sha1Sum(" world")}), " world", kokoro.StatusNoContent, [][2]string{{HeaderUploadOffset, "11"}}, "hello world", true},
+		{"patch checksum mismatch", kokoro.MethodPatch, "/files/partial", with([2]string{HeaderUploadChecksum, sha1Sum(" there")}), " world", StatusChecksumMismatch, nil, "hello", false},
+		{"patch checksum algorithm", kokoro.MethodPatch, "/files/partial", with([2]string{HeaderUploadChecksum, "crc32 AAAAAA=="}), " world", kokoro.StatusBadRequest, nil, "hello", false},
+		{"patch checksum digest", kokoro.MethodPatch, "/files/partial", with([2]string{HeaderUploadChecksum, "sha1 !!"}), " world", kokoro.StatusBadRequest, nil, "hello", false},
+		{"patch locked", kokoro.MethodPatch, "/files/locked", chunk, " world", kokoro.StatusLocked, nil, "hello", false},
+		{"terminate", kokoro.MethodDelete, "/files/partial", nil, "", kokoro.StatusNoContent, nil, "", false},
+		{"terminate unknown", kokoro.MethodDelete, "/files/unknown", nil, "", kokoro.StatusNotFound, nil, "hello", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, info := range []Info{
+				{ID: "partial", Size: 11, Metadata: map[string]string{"filename": "a.txt"}, ExpiresAt: time.Now().Add(time.Hour)},
+				{ID: "expired", Size: 11, ExpiresAt: time.Now().Add(-time.Hour)},
+				{ID: "locked", Size: 11},
+			} {
+				if err := store.Create(info); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := store.Write("partial", 0, strings.NewReader("hello")); err != nil {
+				t.Fatal(err)
+			}
+
+			var complete bool
+			h := New(Config{Store: store, MaxSize: 100, OnComplete: func(c *kokoro.Context, info Info) error {
+				complete = true
+				return nil
+			}})
+			h.locks.Store("locked", struct{}{})
+			s := kokoro.New()
+			h.Mount(s.Group("/files"))
+
+			var ctx fasthttp.RequestCtx
+			ctx.Request.Header.SetMethod(tt.method)
+			ctx.Request.SetRequestURI(tt.path)
+			ctx.Request.Header.SetHost("example.com")
+			ctx.Request.Header.Set(HeaderTusResumable, Version)
+			for _, h := range tt.headers {
+				ctx.Request.Header.Set(h[0], h[1])
+			}
+			ctx.Request.SetBodyString(tt.body)
+			ctx.Request.Header.SetContentLength(len(tt.body))
+			s.Handler(&ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("status = %d, want %d: %s", got, tt.status, ctx.Response.Body())
+			}
+			for _, w := range tt.want {
+				if got := string(ctx.Response.Header.Peek(w[0])); got != w[1] {
+					t.Errorf("%s = %q, want %q", w[0], got, w[1])
+				}
+			}
+			if tt.status == kokoro.StatusCreated {
+				if location := string(ctx.Response.Header.Peek(kokoro.HeaderLocation)); !strings.HasPrefix(location, "http://example.com/files/") || strings.HasSuffix(location, "/") {
+					t.Errorf("Location = %q, want an upload URL below http://example.com/files/", location)
+				}
+			}
+			if complete != tt.complete {
+				t.Errorf("OnComplete called = %v, want %v", complete, tt.complete)
+			}
+			if got, _ := os.ReadFile(store.Path("partial")); string(got) != tt.stored {
+				t.Errorf("stored bytes = %q, want %q", got, tt.stored)
+			}
+			if tt.name == "head expired" {
+				if _, err := store.Get("expired"); err != ErrNotFound {
+					t.Errorf("expired upload kept: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerUpload(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var completed Info
+	s := kokoro.New()
+	New(Config{Store: store, OnComplete: func(c *kokoro.Context, info Info) error {
+		completed = info
+		return nil
+	}}).Mount(s.Group("/files"))
+
+	// Chunks larger than MaxRequestBodySize are streamed to the store.
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go (&fasthttp.Server{Handler: s.Handler, StreamRequestBody: true, MaxRequestBodySize: 1024}).Serve(ln)
+	client := &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+	do := func(method, url string, body string, headers ...[2]string) *fasthttp.Response {
+		t.Helper()
+		req, resp := fasthttp.AcquireRequest(), &fasthttp.Response{}
+		defer fasthttp.ReleaseRequest(req)
+		req.Header.SetMethod(method)
+		req.SetRequestURI(url)
+		req.Header.Set(HeaderTusResumable, Version)
+		for _, h := range headers {
+			req.Header.Set(h[0], h[1])
+		}
+		req.SetBodyString(body)
+		if err := client.Do(req, resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	data := strings.Repeat("0123456789", 1000)
+	resp := do(kokoro.MethodPost, "http://example.com/files", "",
+		[2]string{HeaderUploadLength, strconv.Itoa(len(data))}, [2]string{HeaderUploadMetadata, "filename YS50eHQ=,private"})
+	if resp.StatusCode() != kokoro.StatusCreated {
+		t.Fatalf("create: status = %d, want 201", resp.StatusCode())
+	}
+	location := string(resp.Header.Peek(kokoro.HeaderLocation))
+	if len(resp.Header.Peek(HeaderUploadExpires)) == 0 {
+		t.Error("create: no Upload-Expires header")
+	}
+
+	for offset := 0; offset < len(data); offset += 4000 {
+		end := min(offset+4000, len(data))
+		resp = do(kokoro.MethodPatch, location, data[offset:end],
+			[2]string{kokoro.HeaderContentType, ContentTypeOffsetOctetStream}, [2]string{HeaderUploadOffset, strconv.Itoa(offset)})
+		if resp.StatusCode() != kokoro.StatusNoContent {
+			t.Fatalf("patch at %d: status = %d, want 204: %s", offset, resp.StatusCode(), resp.Body())
+		}
+		if got := string(resp.Header.Peek(HeaderUploadOffset)); got != strconv.Itoa(end) {
+			t.Fatalf("patch at %d: Upload-Offset = %s, want %d", offset, got, end)
+		}
+	}
+
+	resp = do(kokoro.MethodHead, location, "")
+	if got := string(resp.Header.Peek(HeaderUploadMetadata)); got != "filename YS50eHQ=,private" {
+		t.Errorf("head: Upload-Metadata = %q", got)
+	}
+	if len(resp.Header.Peek(HeaderUploadExpires)) != 0 {
+		t.Error("head: Upload-Expires sent for a finished upload")
+	}
+	if completed.Size != int64(len(data)) || completed.Metadata["filename"] != "a.txt" {
+		t.Errorf("OnComplete info = %+v", completed)
+	}
+	if got, _ := os.ReadFile(store.Path(completed.ID)); string(got) != data {
+		t.Errorf("stored %d bytes, want the %d bytes sent", len(got), len(data))
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	tests := []struct {
+		header string
+		want   map[string]string
+		valid  bool
+	}{
+		{"", nil, true},
+		{"filename YS50eHQ=", map[string]string{"filename": "a.txt"}, true},
+		{"filename YS50eHQ=, private", map[string]string{"filename": "a.txt", "private": ""}, true},
+		{"filename !!", nil, false},
+		{"filename YS50eHQ= extra", nil, false},
+		{" YS50eHQ=,", nil, false},
+		{"a YQ==,a Yg==", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parseMetadata(tt.header)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseMetadata(%q) error = %v, want valid %v", tt.header, err, tt.valid)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseMetadata(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("parseMetadata(%q)[%q] = %q, want %q", tt.header, k, got[k], v)
+			}
+		}
+		if tt.valid && tt.header != "" {
+			if round, _ := parseMetadata(formatMetadata(got)); len(round) != len(got) {
+				t.Errorf("formatMetadata(%v) does not round-trip", got)
+			}
+		}
+	}
+}
+
+func TestNewPanicsWithoutStore(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New did not panic")
+		}
+	}()
+	New()
+}